@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
@@ -18,7 +19,17 @@ func main() {
 	// Define command line flags
 	outputFile := flag.String("output", "", "Output file to save results (default: stdout)")
 	limit := flag.Int("limit", 3, "Maximum number of results to return")
-	searchType := flag.String("type", "scraper", "Search type: scraper or api")
+	searchType := flag.String("type", "fallback", "Search type: scraper, api, all, news, fallback or a configured searx instance")
+	language := flag.String("lang", "", "Language code of the results, e.g. en")
+	region := flag.String("region", "", "Country code to localize the results, e.g. us")
+	safeSearch := flag.String("safe", "", "Safe search level: off, moderate or strict")
+	timeRange := flag.String("time", "", "Time range of the results: day, week, month or year")
+	format := flag.String("format", "text", "Format of page content: text, markdown or html-clean")
+	categories := flag.String("categories", "", "Comma separated SearXNG categories, e.g. news,science")
+	engines := flag.String("engines", "", "Comma separated SearXNG engines, e.g. github,stackoverflow")
+	sortOrder := flag.String("sort", "", "Order of the results: relevance or date (default: date for news)")
+	full := flag.Bool("full", false, "Output results with their metadata instead of a url to content map")
+	cursor := flag.String("cursor", "", "Cursor printed by a previous search to get its next results")
 	serverMode := flag.Bool("server", false, "Run in server mode")
 	configPath := flag.String("config", "", "Path to config file")
 	flag.Parse()
@@ -30,7 +41,11 @@ func main() {
 			os.Exit(1)
 		}
 		// Create and start the server
-		srv := server.NewServer(cfg)
+		srv, err := server.NewServer(cfg)
+		if err != nil {
+			slog.Error("Failed to create server", "error", err)
+			os.Exit(1)
+		}
 		if err := srv.Start(cfg.ServerPort); err != nil {
 			slog.Error("Failed to start server", "error", err)
 			os.Exit(1)
@@ -41,34 +56,62 @@ func main() {
 			log.Fatal("Usage: searchagent [options] <search query>")
 		}
 		query := strings.Join(flag.Args(), " ")
-		// Initialize the searcher based on type
-		var s searcher.Searcher
-		var err error
-		switch *searchType {
-		case "api":
-			s, err = searcher.NewSearchService(searcher.SearcherTypeAPI, "")
-		case "scraper":
-			fallthrough
-		default:
-			s, err = searcher.NewSearchService(searcher.SearcherTypeScraper, "")
+		if *limit <= 0 {
+			log.Fatalf("Invalid limit: %d, it must be positive", *limit)
 		}
+		// Config is optional in cli mode, defaults are used without it
+		cfg, err := config.LoadConfig(*configPath)
 		if err != nil {
-			log.Fatalf("Failed to create searcher: %v", err)
+			if *configPath != "" {
+				log.Fatalf("Failed to load config: %v", err)
+			}
+			cfg = &config.Config{}
 		}
-
-		if s == nil {
-			log.Fatalf("Unknown search type: %s", *searchType)
+		registry, err := searcher.NewRegistry(cfg)
+		if err != nil {
+			log.Fatalf("Failed to create searchers: %v", err)
+		}
+		opts := searcher.SearchOptions{
+			Language:   *language,
+			Region:     *region,
+			SafeSearch: searcher.SafeSearch(*safeSearch),
+			TimeRange:  searcher.TimeRange(*timeRange),
+			Format:     searcher.ContentFormat(*format),
+			Categories: []string{*categories},
+			Engines:    []string{*engines},
+			Sort:       searcher.SortOrder(*sortOrder),
+			Cursor:     *cursor,
+		}
+		if err := opts.Validate(); err != nil {
+			log.Fatalf("Invalid search options: %v", err)
 		}
 		// Perform the search
 		ctx := context.Background()
-		results, err := s.Search(ctx, query, *limit)
+		page, err := registry.Search(ctx, *searchType, query, *limit, opts)
 		if err != nil {
 			log.Fatalf("Search error: %v", err)
 		}
-		// Format results as a map [page_link: content]
-		resultsMap := make(map[string]string)
-		for _, result := range results {
-			resultsMap[result.URL] = result.Content
+		slog.Info("Search served", "backend", page.Backend, "cached", page.Cached)
+		// stdout is reserved for the results
+		for _, answer := range page.Answers {
+			fmt.Fprintf(os.Stderr, "answer: %s\n", answer.Text)
+		}
+		if len(page.Corrections) > 0 {
+			fmt.Fprintf(os.Stderr, "did you mean: %s\n", strings.Join(page.Corrections, ", "))
+		}
+		if page.NextCursor != "" {
+			fmt.Fprintf(os.Stderr, "next cursor: %s\n", page.NextCursor)
+		}
+		// Format results as a map [page_link: content], unless metadata is wanted
+		var output any
+		if *full {
+			output = page.Results
+		} else {
+			resultsMap := make(map[string]string)
+			for _, result := range page.Results {
+				resultsMap[result.URL] = result.Content
+			}
+			output = resultsMap
 		}
 		// Output the results
 		if *outputFile != "" {
@@ -80,14 +123,14 @@ func main() {
 			defer file.Close()
 			encoder := json.NewEncoder(file)
 			encoder.SetIndent("", "  ")
-			if err := encoder.Encode(resultsMap); err != nil {
+			if err := encoder.Encode(output); err != nil {
 				log.Fatalf("Error encoding JSON: %v", err)
 			}
 		} else {
 			// Output to stdout
 			encoder := json.NewEncoder(os.Stdout)
 			encoder.SetIndent("", "  ")
-			if err := encoder.Encode(resultsMap); err != nil {
+			if err := encoder.Encode(output); err != nil {
 				log.Fatalf("Error encoding JSON: %v", err)
 			}
 		}
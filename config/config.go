@@ -7,8 +7,47 @@ import (
 )
 
 type Config struct {
-	SEARXAPI string `toml:"SEARX_API"`
-	ServerPort int    `toml:"SERVER_PORT"`
+	SEARXAPI    string `toml:"SEARX_API"`
+	ScraperURL  string `toml:"SCRAPER_URL"`
+	HTTPTimeout int    `toml:"HTTP_TIMEOUT"` // seconds
+	ServerPort  int    `toml:"SERVER_PORT"`
+	// retries of failed requests, MAX_ATTEMPTS counts the first try
+	RetryMaxAttempts int     `toml:"RETRY_MAX_ATTEMPTS"`
+	RetryBaseDelayMS int     `toml:"RETRY_BASE_DELAY_MS"`
+	RetryMaxDelayMS  int     `toml:"RETRY_MAX_DELAY_MS"`
+	RetryJitter      float64 `toml:"RETRY_JITTER"` // fraction of the delay, 0..1
+	RetryStatuses    []int   `toml:"RETRY_STATUSES"`
+	// politeness limits per host in requests per second, negative disables them
+	RateLimit          float64            `toml:"RATE_LIMIT"`
+	RateBurst          int                `toml:"RATE_BURST"`
+	RateLimitOverrides map[string]float64 `toml:"RATE_LIMIT_OVERRIDES"`
+	// result page fetching of the scraper
+	FetchConcurrency int `toml:"FETCH_CONCURRENCY"`
+	FetchDeadline    int `toml:"FETCH_DEADLINE"` // seconds, for all pages of one search
+	// bytes read of a response at most, longer result pages are cut
+	MaxBodyBytes int `toml:"MAX_BODY_BYTES"`
+	// "main" keeps the main content block of fetched pages, "page" the whole text
+	ExtractMode string `toml:"EXTRACT_MODE"`
+	// skip result pages disallowed by robots.txt
+	RobotsEnabled bool `toml:"ROBOTS_ENABLED"`
+	// seconds the scraper leaves DuckDuckGo alone after being blocked
+	BlockCooldown int `toml:"BLOCK_COOLDOWN"`
+	// in-memory caching of search results and page content
+	CacheTTL      int `toml:"CACHE_TTL"` // seconds, negative disables caching
+	CacheSize     int `toml:"CACHE_SIZE"`
+	PageCacheSize int `toml:"PAGE_CACHE_SIZE"`
+	// directory persisting fetched pages between restarts, empty disables it
+	PageCacheDir   string `toml:"PAGE_CACHE_DIR"`
+	PageCacheMaxMB int    `toml:"PAGE_CACHE_MAX_MB"`
+	// searx categories and engines queried when a search names none
+	SearXCategories []string `toml:"SEARX_CATEGORIES"`
+	SearXEngines    []string `toml:"SEARX_ENGINES"`
+	// additional searx instances, registered as search types under their names
+	SearXInstances map[string]string `toml:"SEARX_INSTANCES"`
+	// search types combined by the "all" search type
+	AggregateTypes []string `toml:"AGGREGATE_TYPES"`
+	// search types tried in order by the default "fallback" search type
+	FallbackChain []string `toml:"FALLBACK_CHAIN"`
 }
 
 func LoadConfig(fn string) (*Config, error) {
@@ -6,6 +6,7 @@ require (
 	github.com/BurntSushi/toml v1.5.0
 	github.com/PuerkitoBio/goquery v1.9.2
 	golang.org/x/net v0.24.0
+	golang.org/x/text v0.14.0
 )
 
 require github.com/andybalholm/cascadia v1.3.2 // indirect
@@ -3,6 +3,8 @@ package models
 type ToolArgProps struct {
 	Type        string `json:"type"`
 	Description string `json:"description"`
+	// Items describes the elements of array arguments
+	Items *ToolArgProps `json:"items,omitempty"`
 }
 
 type ToolFuncParams struct {
@@ -0,0 +1,265 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion,
+// 60 is the value from the original paper
+const rrfK = 60
+
+// NamedSearcher is a searcher together with its search type
+type NamedSearcher struct {
+	Name     string
+	Searcher Searcher
+}
+
+// AggregateSearcher fans a query out to several searchers in parallel,
+// merges results pointing to the same page and orders them with
+// reciprocal rank fusion
+type AggregateSearcher struct {
+	searchers []NamedSearcher
+}
+
+// NewAggregateSearcher creates a meta-searcher over the given searchers
+func NewAggregateSearcher(searchers []NamedSearcher) *AggregateSearcher {
+	return &AggregateSearcher{searchers: searchers}
+}
+
+// childPage is the outcome of one searcher of the aggregate. cursor
+// asked for the page, the first skip results were returned before.
+type childPage struct {
+	cursor string
+	skip   int
+	page   *ResultPage
+	err    error
+	// keys of the results taking part in the fusion, in rank order
+	keys []string
+}
+
+// fusedResult is a merged result with its fusion score
+type fusedResult struct {
+	result SearchResult
+	score  float64
+	order  int
+}
+
+// Search asks every searcher for up to limit results and returns the best
+// limit of the merged list. The cursor keeps the page every searcher is on
+// and how many of its results were returned, so results cut off by the
+// limit come on a later page. A searcher's page is asked for again then,
+// which the cache in front of every searcher answers.
+func (a *AggregateSearcher) Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]childPage, len(a.searchers))
+	var wg sync.WaitGroup
+	for i, ns := range a.searchers {
+		if opts.Cursor != "" && !cur.Has(ns.Name) {
+			// This searcher ran out of results on a previous page
+			continue
+		}
+		skip, err := cursorInt(cur, childSkipKey(ns.Name), 0)
+		if err != nil {
+			return nil, err
+		}
+		childOpts := opts
+		childOpts.Cursor = cur.Get(ns.Name)
+		wg.Go(func() {
+			page, err := ns.Searcher.Search(ctx, query, limit, childOpts)
+			pages[i] = childPage{cursor: childOpts.Cursor, skip: skip, page: page, err: err}
+		})
+	}
+	wg.Wait()
+
+	var errs []error
+	next := url.Values{}
+	fused := make(map[string]*fusedResult)
+	var answered []string
+	extras := &ResultPage{}
+	cached := true
+	for i, cp := range pages {
+		if cp.err != nil {
+			errs = append(errs, cp.err)
+			continue
+		}
+		if cp.page == nil {
+			continue
+		}
+		answered = append(answered, a.searchers[i].Name)
+		cached = cached && cp.page.Cached
+		if cp.skip == 0 {
+			// Answers and the like came with the first look at the page
+			mergeExtras(extras, cp.page)
+		}
+		for rank, result := range cp.page.Results[min(cp.skip, len(cp.page.Results)):] {
+			key := normalizeURL(result.URL)
+			pages[i].keys = append(pages[i].keys, key)
+			score := 1.0 / float64(rrfK+rank+1)
+			if f, ok := fused[key]; ok {
+				f.score += score
+				f.result = mergeResults(f.result, result)
+				continue
+			}
+			fused[key] = &fusedResult{result: result, score: score, order: len(fused)}
+		}
+	}
+	if len(answered) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	ranked := make([]*fusedResult, 0, len(fused))
+	for _, f := range fused {
+		ranked = append(ranked, f)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].order < ranked[j].order
+	})
+	results := make([]SearchResult, 0, min(limit, len(ranked)))
+	returned := make(map[string]bool, limit)
+	for _, f := range ranked[:min(limit, len(ranked))] {
+		results = append(results, f.result)
+		returned[normalizeURL(f.result.URL)] = true
+	}
+	for i, cp := range pages {
+		if cp.err != nil || cp.page == nil {
+			continue
+		}
+		name := a.searchers[i].Name
+		// Only a run of returned results from the top counts as seen, a
+		// result returned after one that was cut off may come again
+		taken := 0
+		for taken < len(cp.keys) && returned[cp.keys[taken]] {
+			taken++
+		}
+		switch {
+		case taken < len(cp.keys):
+			next.Set(name, cp.cursor)
+			next.Set(childSkipKey(name), strconv.Itoa(cp.skip+taken))
+		case cp.page.NextCursor != "":
+			next.Set(name, cp.page.NextCursor)
+		}
+	}
+	var nextCursor string
+	if len(next) > 0 {
+		nextCursor = encodeCursor(next)
+	}
+	extras.Results = results
+	extras.NextCursor = nextCursor
+	extras.Backend = strings.Join(answered, ",")
+	extras.Cached = len(answered) > 0 && cached
+	return extras, nil
+}
+
+// childSkipKey is the cursor key of the number of a searcher's results
+// returned from its current page
+func childSkipKey(name string) string {
+	return cursorSkip + "." + name
+}
+
+// mergeExtras adds the answers, infoboxes and hints of a child's page,
+// suggestions and corrections only once
+func mergeExtras(dst, src *ResultPage) {
+	dst.Answers = append(dst.Answers, src.Answers...)
+	dst.Infoboxes = append(dst.Infoboxes, src.Infoboxes...)
+	dst.UnresponsiveEngines = append(dst.UnresponsiveEngines, src.UnresponsiveEngines...)
+	for _, suggestion := range src.Suggestions {
+		if !slices.Contains(dst.Suggestions, suggestion) {
+			dst.Suggestions = append(dst.Suggestions, suggestion)
+		}
+	}
+	for _, correction := range src.Corrections {
+		if !slices.Contains(dst.Corrections, correction) {
+			dst.Corrections = append(dst.Corrections, correction)
+		}
+	}
+}
+
+// mergeResults combines two results for the same page,
+// keeping the fields of the better ranked one where both are set
+func mergeResults(kept, other SearchResult) SearchResult {
+	if kept.Title == "" {
+		kept.Title = other.Title
+	}
+	// Fetched page content beats a short snippet, the page's
+	// fetch details come along with it
+	if len(other.Content) > len(kept.Content) {
+		kept.Content = other.Content
+		kept.FinalURL = other.FinalURL
+		kept.ContentLength = other.ContentLength
+		kept.FetchStatus = other.FetchStatus
+		kept.Flags = other.Flags
+	}
+	if kept.Snippet == "" {
+		kept.Snippet = other.Snippet
+	}
+	// A copy, the children's results may sit in a cache
+	engines := slices.Clone(kept.Engines)
+	for _, engine := range other.Engines {
+		if !slices.Contains(engines, engine) {
+			engines = append(engines, engine)
+		}
+	}
+	kept.Engines = engines
+	if kept.PublishedDate == nil {
+		kept.PublishedDate = other.PublishedDate
+	}
+	if kept.Author == "" {
+		kept.Author = other.Author
+	}
+	if kept.SiteName == "" {
+		kept.SiteName = other.SiteName
+	}
+	if kept.Source == "" {
+		kept.Source = other.Source
+	}
+	if kept.Language == "" {
+		kept.Language = other.Language
+	}
+	if kept.CanonicalURL == "" {
+		kept.CanonicalURL = other.CanonicalURL
+	}
+	if kept.Metadata == nil {
+		kept.Metadata = other.Metadata
+	}
+	return kept
+}
+
+// normalizeURL returns a key under which urls of the same page are equal:
+// lowercase host without 'www.', no fragment, no tracking params,
+// sorted query and no trailing slash
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	q := u.Query()
+	for key := range q {
+		lk := strings.ToLower(key)
+		if strings.HasPrefix(lk, "utm_") || lk == "fbclid" || lk == "gclid" {
+			q.Del(key)
+		}
+	}
+	key := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if len(q) > 0 {
+		// Encode sorts by key
+		key += "?" + q.Encode()
+	}
+	return key
+}
@@ -0,0 +1,157 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// listSearcher pages through a fixed list of urls, or fails with err
+type listSearcher struct {
+	urls []string
+	err  error
+}
+
+func (s *listSearcher) Search(_ context.Context, _ string, limit int, opts SearchOptions) (*ResultPage, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	skip, err := cursorInt(cur, cursorSkip, 0)
+	if err != nil {
+		return nil, err
+	}
+	taken, nextSkip, more := takePage(s.urls, skip, limit)
+	page := &ResultPage{}
+	for _, u := range taken {
+		page.Results = append(page.Results, SearchResult{URL: u, Title: u})
+	}
+	if more {
+		page.NextCursor = encodeCursor(url.Values{cursorSkip: {strconv.Itoa(nextSkip)}})
+	}
+	return page, nil
+}
+
+func resultURLs(results []SearchResult) []string {
+	urls := make([]string, 0, len(results))
+	for _, r := range results {
+		urls = append(urls, r.URL)
+	}
+	return urls
+}
+
+func TestAggregateFusion(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists [][]string
+		limit int
+		want  []string
+	}{
+		{"single searcher keeps its order", [][]string{{"https://a.com", "https://b.com"}}, 10, []string{"https://a.com", "https://b.com"}},
+		{"ties go to the first seen", [][]string{{"https://a.com"}, {"https://b.com"}}, 10, []string{"https://a.com", "https://b.com"}},
+		{"found by both ranks first", [][]string{{"https://a.com", "https://b.com"}, {"https://c.com", "https://b.com"}}, 10, []string{"https://b.com", "https://a.com", "https://c.com"}},
+		{"duplicates merge by normalized url", [][]string{{"https://www.a.com/x/?utm_source=q"}, {"https://a.com/x#top"}}, 10, []string{"https://www.a.com/x/?utm_source=q"}},
+		{"limit cuts the merged list", [][]string{{"https://a.com", "https://b.com"}, {"https://c.com", "https://d.com"}}, 3, []string{"https://a.com", "https://c.com", "https://b.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var searchers []NamedSearcher
+			for i, urls := range tt.lists {
+				searchers = append(searchers, NamedSearcher{Name: strconv.Itoa(i), Searcher: &listSearcher{urls: urls}})
+			}
+			page, err := NewAggregateSearcher(searchers).Search(context.Background(), "q", tt.limit, SearchOptions{})
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if got := resultURLs(page.Results); !slices.Equal(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregatePaging(t *testing.T) {
+	a := &listSearcher{urls: []string{"https://a.com/1", "https://a.com/2", "https://a.com/3"}}
+	b := &listSearcher{urls: []string{"https://b.com/1", "https://b.com/2"}}
+	agg := NewAggregateSearcher([]NamedSearcher{{Name: "a", Searcher: a}, {Name: "b", Searcher: b}})
+	var all []string
+	opts := SearchOptions{}
+	for range 10 {
+		page, err := agg.Search(context.Background(), "q", 2, opts)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		all = append(all, resultURLs(page.Results)...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	want := []string{"https://a.com/1", "https://b.com/1", "https://a.com/2", "https://b.com/2", "https://a.com/3"}
+	if !slices.Equal(all, want) {
+		t.Errorf("pages gave %v, want %v", all, want)
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	failed := &listSearcher{err: ErrBackendUnavailable}
+	ok := &listSearcher{urls: []string{"https://a.com"}}
+	page, err := NewAggregateSearcher([]NamedSearcher{{Name: "failed", Searcher: failed}, {Name: "ok", Searcher: ok}}).
+		Search(context.Background(), "q", 10, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search with one failed searcher: %v", err)
+	}
+	if page.Backend != "ok" || len(page.Results) != 1 {
+		t.Errorf("page = %+v, want the result of ok", page)
+	}
+	_, err = NewAggregateSearcher([]NamedSearcher{{Name: "failed", Searcher: failed}}).
+		Search(context.Background(), "q", 10, SearchOptions{})
+	if !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Search with every searcher failed = %v, want ErrBackendUnavailable", err)
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	kept := SearchResult{URL: "https://a.com", Title: "A", Snippet: "short", Engines: []string{"google"}}
+	other := SearchResult{URL: "https://a.com/", Title: "Other", Content: "the fetched page", Engines: []string{"bing", "google"}, Author: "Ann"}
+	got := mergeResults(kept, other)
+	if got.Title != "A" || got.Snippet != "short" {
+		t.Errorf("merged title and snippet = %q, %q, want the kept ones", got.Title, got.Snippet)
+	}
+	if got.Content != "the fetched page" || got.Author != "Ann" {
+		t.Errorf("merged content and author = %q, %q, want the other's", got.Content, got.Author)
+	}
+	if !slices.Equal(got.Engines, []string{"google", "bing"}) {
+		t.Errorf("merged engines = %v, want [google bing]", got.Engines)
+	}
+	if !slices.Equal(kept.Engines, []string{"google"}) {
+		t.Errorf("merging changed the kept engines to %v", kept.Engines)
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://www.Example.com/path/", "http://example.com/path", true},
+		{"https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2", true},
+		{"https://example.com/a?utm_source=x&fbclid=y", "https://example.com/a", true},
+		{"https://example.com/a#section", "https://example.com/a", true},
+		{"https://example.com:443/a", "https://example.com/a", true},
+		{"https://example.com:8080/a", "https://example.com/a", false},
+		{"https://example.com/a?id=1", "https://example.com/a?id=2", false},
+		{"https://example.com/A", "https://example.com/a", false},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.a) == normalizeURL(tt.b); got != tt.same {
+			t.Errorf("normalizeURL(%q) == normalizeURL(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package searcher
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache defaults
+const (
+	defaultCacheTTL      = 10 * time.Minute
+	defaultCacheSize     = 256
+	defaultPageCacheSize = 512
+)
+
+// lruCache is a size bounded map evicting least recently used entries,
+// entries expire after ttl
+type lruCache[V any] struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[string]*list.Element
+	order *list.List // front is the most recently used
+}
+
+type cacheEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+func newLRUCache[V any](size int, ttl time.Duration) *lruCache[V] {
+	return &lruCache[V]{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// Get returns the value stored under key unless it expired
+func (c *lruCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := el.Value.(*cacheEntry[V])
+	if time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// Set stores the value, evicting the least recently used entry when full
+func (c *lruCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry[V])
+		entry.value = value
+		entry.expires = expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry[V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry[V]).key)
+	}
+}
+
+// CachedSearcher keeps result pages of the wrapped searcher in memory,
+// so repeated searches don't reach the backend
+type CachedSearcher struct {
+	searcher Searcher
+	cache    *lruCache[ResultPage]
+}
+
+// NewCachedSearcher wraps the searcher with a cache of up to size pages
+// living for ttl
+func NewCachedSearcher(s Searcher, size int, ttl time.Duration) *CachedSearcher {
+	return &CachedSearcher{
+		searcher: s,
+		cache:    newLRUCache[ResultPage](size, ttl),
+	}
+}
+
+// Search returns the cached page for the same query, options and limit,
+// or searches and caches the page. Errors are not cached.
+func (c *CachedSearcher) Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	key := searchCacheKey(query, limit, opts)
+	if page, ok := c.cache.Get(key); ok {
+		page.Results = slices.Clone(page.Results)
+		page.Cached = true
+		return &page, nil
+	}
+	page, err := c.searcher.Search(ctx, query, limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	stored := *page
+	stored.Results = slices.Clone(page.Results)
+	c.cache.Set(key, stored)
+	return page, nil
+}
+
+// searchCacheKey builds the cache key from the normalized query,
+// every option and the limit
+func searchCacheKey(query string, limit int, opts SearchOptions) string {
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	return strings.Join([]string{
+		query,
+		strconv.Itoa(limit),
+		opts.Language,
+		opts.Region,
+		string(opts.SafeSearch),
+		string(opts.TimeRange),
+		string(opts.Format),
+		strings.Join(opts.Categories, ","),
+		strings.Join(opts.Engines, ","),
+		opts.Cursor,
+	}, "\x00")
+}
@@ -0,0 +1,109 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache[int](2, time.Minute)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+	tests := []struct {
+		key    string
+		want   int
+		wantOK bool
+	}{
+		{"a", 1, true},
+		{"b", 0, false}, // least recently used, evicted by c
+		{"c", 3, true},
+	}
+	for _, tt := range tests {
+		if got, ok := c.Get(tt.key); got != tt.want || ok != tt.wantOK {
+			t.Errorf("Get(%q) = %d, %v, want %d, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	c.Set("a", 10)
+	if got, _ := c.Get("a"); got != 10 {
+		t.Errorf("Get after update = %d, want 10", got)
+	}
+
+	expired := newLRUCache[int](2, -time.Second)
+	expired.Set("a", 1)
+	if _, ok := expired.Get("a"); ok {
+		t.Error("Get returned an expired entry")
+	}
+	if expired.order.Len() != 0 {
+		t.Errorf("expired entry stayed in the cache")
+	}
+}
+
+func TestSearchCacheKey(t *testing.T) {
+	base := SearchOptions{Language: "en", Format: FormatText}
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		opts  SearchOptions
+		same  bool
+	}{
+		{"case and spaces", "  Go   GENERICS ", 5, base, true},
+		{"other limit", "go generics", 10, base, false},
+		{"other language", "go generics", 5, SearchOptions{Language: "de", Format: FormatText}, false},
+		{"other format", "go generics", 5, SearchOptions{Language: "en", Format: FormatMarkdown}, false},
+		{"engines", "go generics", 5, SearchOptions{Language: "en", Format: FormatText, Engines: []string{"github"}}, false},
+		{"cursor", "go generics", 5, SearchOptions{Language: "en", Format: FormatText, Cursor: "abc"}, false},
+	}
+	want := searchCacheKey("go generics", 5, base)
+	for _, tt := range tests {
+		if got := searchCacheKey(tt.query, tt.limit, tt.opts) == want; got != tt.same {
+			t.Errorf("%s: same key is %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
+
+// countingSearcher counts the searches reaching it
+type countingSearcher struct {
+	listSearcher
+	calls int
+}
+
+func (s *countingSearcher) Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	s.calls++
+	return s.listSearcher.Search(ctx, query, limit, opts)
+}
+
+func TestCachedSearcher(t *testing.T) {
+	backend := &countingSearcher{listSearcher: listSearcher{urls: []string{"https://a.com", "https://b.com"}}}
+	c := NewCachedSearcher(backend, 10, time.Minute)
+	first, err := c.Search(context.Background(), "q", 2, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	first.Results[0].Title = "changed by the caller"
+	second, err := c.Search(context.Background(), "Q", 2, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if backend.calls != 1 || first.Cached || !second.Cached {
+		t.Errorf("calls = %d, cached = %v then %v, want 1 call and the second page cached", backend.calls, first.Cached, second.Cached)
+	}
+	if second.Results[0].Title != "https://a.com" {
+		t.Errorf("cached result title = %q, changes of the caller leaked into the cache", second.Results[0].Title)
+	}
+
+	failing := &countingSearcher{listSearcher: listSearcher{err: ErrBackendUnavailable}}
+	c = NewCachedSearcher(failing, 10, time.Minute)
+	for range 2 {
+		if _, err := c.Search(context.Background(), "q", 2, SearchOptions{}); !errors.Is(err, ErrBackendUnavailable) {
+			t.Fatalf("Search = %v, want ErrBackendUnavailable", err)
+		}
+	}
+	if failing.calls != 2 {
+		t.Errorf("failing backend got %d calls, want 2, errors must not be cached", failing.calls)
+	}
+}
@@ -0,0 +1,62 @@
+package searcher
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// decodeBody transcodes a page to UTF-8. The charset comes from a BOM,
+// the Content-Type header or a <meta> tag in the head, in that order.
+func decodeBody(body []byte, contentType string) []byte {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain && name == "windows-1252" && utf8.Valid(body) {
+		// The guess only looks at the first KB, undeclared pages
+		// that are valid UTF-8 as a whole almost always are
+		return body
+	}
+	decoded := body
+	if name != "utf-8" {
+		var err error
+		if decoded, _, err = transform.Bytes(enc.NewDecoder(), body); err != nil {
+			return body
+		}
+	}
+	return bytes.TrimPrefix(decoded, []byte(byteOrderMark))
+}
+
+// xmlEncoding finds the encoding named by an XML declaration
+var xmlEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// decodeXMLBody transcodes an XML document to UTF-8. The charset comes
+// from a BOM, the Content-Type header or the XML declaration, in that order.
+func decodeXMLBody(body []byte, contentType string) []byte {
+	if _, _, certain := charset.DetermineEncoding(body, contentType); !certain {
+		if m := xmlEncoding.FindSubmatch(body); m != nil {
+			if enc, _ := charset.Lookup(string(m[1])); enc != nil {
+				if decoded, _, err := transform.Bytes(enc.NewDecoder(), body); err == nil {
+					return decoded
+				}
+			}
+		}
+	}
+	return decodeBody(body, contentType)
+}
+
+// newXMLDecoder reads a document decodeXMLBody made UTF-8, whatever
+// encoding its declaration still names
+func newXMLDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+// byteOrderMark is U+FEFF encoded as UTF-8
+const byteOrderMark = "\xef\xbb\xbf"
@@ -0,0 +1,35 @@
+package searcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractDocumentXMLCharset(t *testing.T) {
+	feed := "<?xml version=\"1.0\" encoding=\"%s\"?><rss><channel><title>Caf\xe9</title>" +
+		"<item><title>Cr\xe8me</title><link>https://example.com/a</link></item></channel></rss>"
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        []string
+	}{
+		{"declared latin1 feed", strings.Replace(feed, "%s", "ISO-8859-1", 1), "application/rss+xml", []string{"Café", "Crème"}},
+		{"header charset wins", strings.Replace(feed, "%s", "UTF-8", 1), "application/rss+xml; charset=windows-1252", []string{"Café", "Crème"}},
+		{"utf8 feed", "<?xml version=\"1.0\"?><rss><channel><title>Café</title></channel></rss>", "application/rss+xml", []string{"Café"}},
+		{"declared latin1 outline", "<?xml version='1.0' encoding='ISO-8859-1'?><doc><name>Jos\xe9</name></doc>", "application/xml", []string{"name: José"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := extractDocument([]byte(tt.body), tt.contentType, "https://example.com/feed", ExtractPage, FormatText)
+			if err != nil {
+				t.Fatalf("extractDocument: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("extractDocument = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
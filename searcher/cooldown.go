@@ -0,0 +1,40 @@
+package searcher
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultBlockCooldown = 5 * time.Minute
+
+// cooldown keeps a backend from being queried for a while
+// after it blocked or rate limited us
+type cooldown struct {
+	mu       sync.Mutex
+	until    time.Time
+	duration time.Duration
+}
+
+// remaining returns how long the backend is still backed off
+func (c *cooldown) remaining() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Until(c.until)
+}
+
+// trip backs off for the wait asked for in err, at least for the
+// configured duration, and records the wait on err for the caller
+func (c *cooldown) trip(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wait := c.duration
+	var be *BackendError
+	if errors.As(err, &be) {
+		wait = max(wait, be.RetryAfter)
+		be.RetryAfter = wait
+	}
+	if until := time.Now().Add(wait); until.After(c.until) {
+		c.until = until
+	}
+}
@@ -0,0 +1,61 @@
+package searcher
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor param holding how many results of the page were already returned
+const cursorSkip = "skip"
+
+// encodeCursor packs backend specific paging state into an opaque string
+func encodeCursor(v url.Values) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(v.Encode()))
+}
+
+// decodeCursor unpacks a cursor made by encodeCursor,
+// an empty cursor gives empty values
+func decodeCursor(c string) (url.Values, error) {
+	if c == "" {
+		return url.Values{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	v, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return v, nil
+}
+
+// cursorInt reads a non-negative integer param of a decoded cursor,
+// missing params give def
+func cursorInt(v url.Values, key string, def int) (int, error) {
+	s := v.Get(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return n, nil
+}
+
+// takePage returns up to limit items of a backend page after skipping the
+// ones already returned, and the skip value to continue on the same page.
+// more reports whether the page has items left.
+func takePage[T any](items []T, skip, limit int) (taken []T, nextSkip int, more bool) {
+	if skip >= len(items) {
+		return nil, skip, false
+	}
+	end := min(skip+limit, len(items))
+	return items[skip:end], end, end < len(items)
+}
@@ -0,0 +1,77 @@
+package searcher
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []url.Values{
+		{},
+		{"pageno": {"2"}},
+		{"pageno": {"1"}, cursorSkip: {"5"}},
+		{"action": {"/html/"}, "form": {"q=go+generics&s=30&dc=31"}},
+		{"backend": {"api"}, "cursor": {"cGFnZW5vPTI"}},
+	}
+	for _, v := range tests {
+		got, err := decodeCursor(encodeCursor(v))
+		if err != nil {
+			t.Fatalf("decodeCursor(encodeCursor(%v)): %v", v, err)
+		}
+		if got.Encode() != v.Encode() {
+			t.Errorf("round trip of %v gave %v", v, got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, c := range []string{"!!!", "a b", encodeCursor(nil) + "%"} {
+		if _, err := decodeCursor(c); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) error = %v, want ErrInvalidCursor", c, err)
+		}
+	}
+	if v, err := decodeCursor(""); err != nil || len(v) != 0 {
+		t.Errorf("decodeCursor(\"\") = %v, %v, want empty values", v, err)
+	}
+}
+
+func TestCursorInt(t *testing.T) {
+	v := url.Values{"n": {"3"}, "neg": {"-1"}, "bad": {"x"}}
+	if n, err := cursorInt(v, "n", 0); n != 3 || err != nil {
+		t.Errorf("cursorInt(n) = %d, %v, want 3", n, err)
+	}
+	if n, err := cursorInt(v, "missing", 7); n != 7 || err != nil {
+		t.Errorf("cursorInt(missing) = %d, %v, want 7", n, err)
+	}
+	for _, key := range []string{"neg", "bad"} {
+		if _, err := cursorInt(v, key, 0); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursorInt(%s) error = %v, want ErrInvalidCursor", key, err)
+		}
+	}
+}
+
+func TestTakePage(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		skip, limit int
+		want        []int
+		nextSkip    int
+		more        bool
+	}{
+		{0, 2, []int{1, 2}, 2, true},
+		{2, 2, []int{3, 4}, 4, true},
+		{4, 2, []int{5}, 5, false},
+		{0, 5, []int{1, 2, 3, 4, 5}, 5, false},
+		{5, 2, nil, 5, false},
+		{9, 2, nil, 9, false},
+	}
+	for _, tt := range tests {
+		got, nextSkip, more := takePage(items, tt.skip, tt.limit)
+		if !slices.Equal(got, tt.want) || nextSkip != tt.nextSkip || more != tt.more {
+			t.Errorf("takePage(skip %d, limit %d) = %v, %d, %v, want %v, %d, %v",
+				tt.skip, tt.limit, got, nextSkip, more, tt.want, tt.nextSkip, tt.more)
+		}
+	}
+}
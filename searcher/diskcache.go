@@ -0,0 +1,154 @@
+package searcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDiskCacheBytes = 256 << 20
+
+// diskEntry is a fetched page persisted by diskPageCache
+type diskEntry struct {
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Length       int64     `json:"length,omitempty"`
+	// Body is the document as it was served, up to the body limit
+	Body      []byte   `json:"body"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Meta      pageMeta `json:"meta"`
+	// Format is the format Content was extracted in
+	Format  ContentFormat `json:"format,omitempty"`
+	Content string        `json:"content"`
+}
+
+// diskPageCache persists fetched pages in a directory, one file per url.
+// Least recently used files are evicted once the directory grows past
+// maxBytes, file modification time serves as the usage time.
+type diskPageCache struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+}
+
+// newDiskPageCache creates the cache directory if needed
+func newDiskPageCache(dir string, maxBytes int64) (*diskPageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultDiskCacheBytes
+	}
+	return &diskPageCache{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// path returns the file of the url, hashed to stay a valid file name
+func (c *diskPageCache) path(pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the stored page or nil, marking it as recently used
+func (c *diskPageCache) Get(pageURL string) *diskEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(pageURL)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != pageURL {
+		// Broken file, drop it
+		os.Remove(path)
+		return nil
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return &entry
+}
+
+// Put stores the page and evicts old pages if the cache got too big
+func (c *diskPageCache) Put(entry *diskEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(entry.URL)
+	// Write to a temp file first, so a crash never leaves half a page
+	tmp, err := os.CreateTemp(c.dir, "page-*.tmp")
+	if err != nil {
+		slog.Warn("failed to write page cache", "error", err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		slog.Warn("failed to write page cache", "error", err)
+		return
+	}
+	c.evict()
+}
+
+// evict removes least recently used pages until the cache fits maxBytes
+func (c *diskPageCache) evict() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	files := make([]file, 0, len(entries))
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{
+			path:    filepath.Join(c.dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		total += info.Size()
+	}
+	if total <= c.maxBytes {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package searcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskPageCache(t *testing.T) {
+	c, err := newDiskPageCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &diskEntry{URL: "https://a.com/", ETag: `"v1"`, Body: []byte("<p>a</p>"), Content: "a", FetchedAt: time.Now()}
+	c.Put(entry)
+	got := c.Get("https://a.com/")
+	if got == nil || got.ETag != entry.ETag || string(got.Body) != string(entry.Body) {
+		t.Fatalf("Get = %+v, want the stored entry", got)
+	}
+	if c.Get("https://b.com/") != nil {
+		t.Error("Get of a page never stored returned an entry")
+	}
+	if err := os.WriteFile(c.path("https://broken.com/"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("https://broken.com/") != nil {
+		t.Error("Get of a broken file returned an entry")
+	}
+	if _, err := os.Stat(c.path("https://broken.com/")); !os.IsNotExist(err) {
+		t.Error("broken file was kept")
+	}
+}
+
+func TestDiskPageCacheEviction(t *testing.T) {
+	c, err := newDiskPageCache(t.TempDir(), 3500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(strings.Repeat("x", 1000))
+	old := time.Now().Add(-time.Hour)
+	for i, u := range []string{"https://a.com/", "https://b.com/"} {
+		c.Put(&diskEntry{URL: u, Body: body, FetchedAt: time.Now()})
+		// Usage times a second apart, a before b
+		at := old.Add(time.Duration(i) * time.Second)
+		os.Chtimes(c.path(u), at, at)
+	}
+	c.Put(&diskEntry{URL: "https://c.com/", Body: body, FetchedAt: time.Now()})
+	tests := []struct {
+		url  string
+		kept bool
+	}{
+		{"https://a.com/", false},
+		{"https://b.com/", true},
+		{"https://c.com/", true},
+	}
+	for _, tt := range tests {
+		if got := c.Get(tt.url) != nil; got != tt.kept {
+			t.Errorf("%s kept = %v, want %v", tt.url, got, tt.kept)
+		}
+	}
+}
+
+func TestDiskCacheRevalidation(t *testing.T) {
+	requests, conditional := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("cached page text"))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name            string
+		fresh           time.Duration
+		wantRequests    int
+		wantConditional int
+		wantStatus      int
+	}{
+		{"fresh copy is used as is", time.Hour, 1, 0, http.StatusOK},
+		{"stale copy is revalidated", -time.Second, 2, 1, http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, conditional = 0, 0
+			ws := NewWebScraper("", srv.Client())
+			if err := ws.SetDiskCache(t.TempDir(), 0, tt.fresh); err != nil {
+				t.Fatal(err)
+			}
+			var page pageContent
+			for range 2 {
+				var err error
+				if page, err = ws.extractContentFromURL(context.Background(), srv.URL+"/page", FormatText); err != nil {
+					t.Fatalf("extractContentFromURL: %v", err)
+				}
+			}
+			if page.content != "cached page text" {
+				t.Errorf("content = %q, want the page text", page.content)
+			}
+			if requests != tt.wantRequests || conditional != tt.wantConditional || page.status != tt.wantStatus {
+				t.Errorf("requests = %d, conditional = %d, status = %d, want %d, %d, %d",
+					requests, conditional, page.status, tt.wantRequests, tt.wantConditional, tt.wantStatus)
+			}
+		})
+	}
+}
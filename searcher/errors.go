@@ -0,0 +1,115 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Kinds of search failures, match them with errors.Is
+var (
+	ErrRateLimited        = errors.New("rate limited")
+	ErrBlocked            = errors.New("blocked by captcha or bot detection")
+	ErrBackendUnavailable = errors.New("backend unavailable")
+	ErrInvalidResponse    = errors.New("invalid response")
+	ErrNoResults          = errors.New("no results")
+	ErrTimeout            = errors.New("timeout")
+)
+
+// BackendError is a failure of a search backend or a fetched page,
+// Err is one of the kinds above
+type BackendError struct {
+	Backend    string
+	StatusCode int
+	// RetryAfter is the wait the backend asked for, zero if it didn't
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *BackendError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: %v (status %d)", e.Backend, e.Err, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %v", e.Backend, e.Err)
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the longest wait asked for by any backend in err
+func RetryAfter(err error) time.Duration {
+	var longest time.Duration
+	var walk func(error)
+	walk = func(err error) {
+		var be *BackendError
+		if errors.As(err, &be) && be.RetryAfter > longest {
+			longest = be.RetryAfter
+		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				walk(e)
+			}
+		}
+	}
+	walk(err)
+	return longest
+}
+
+// statusError classifies a non-successful response of a backend
+func statusError(backend string, resp *http.Response) error {
+	kind := ErrInvalidResponse
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	case resp.StatusCode == http.StatusForbidden:
+		kind = ErrBlocked
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		kind = ErrTimeout
+	case resp.StatusCode >= 500:
+		kind = ErrBackendUnavailable
+	}
+	return &BackendError{
+		Backend:    backend,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Err:        kind,
+	}
+}
+
+// transportError classifies a failed request of a backend.
+// Cancellation by the caller is returned as it is.
+func transportError(backend string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	kind := ErrBackendUnavailable
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		kind = ErrTimeout
+	}
+	return &BackendError{
+		Backend: backend,
+		Err:     fmt.Errorf("%w: %w", kind, err),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
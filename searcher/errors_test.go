@@ -0,0 +1,64 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		want       error
+		wantWait   time.Duration
+	}{
+		{http.StatusTooManyRequests, "30", ErrRateLimited, 30 * time.Second},
+		{http.StatusForbidden, "", ErrBlocked, 0},
+		{http.StatusGatewayTimeout, "", ErrTimeout, 0},
+		{http.StatusServiceUnavailable, "5", ErrBackendUnavailable, 5 * time.Second},
+		{http.StatusNotFound, "", ErrInvalidResponse, 0},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+		err := statusError("api", resp)
+		if !errors.Is(err, tt.want) || RetryAfter(err) != tt.wantWait {
+			t.Errorf("statusError(%d) = %v waiting %v, want %v waiting %v", tt.status, err, RetryAfter(err), tt.want, tt.wantWait)
+		}
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"cancelled", context.Canceled, context.Canceled},
+		{"deadline", context.DeadlineExceeded, ErrTimeout},
+		{"refused", errors.New("connection refused"), ErrBackendUnavailable},
+	}
+	for _, tt := range tests {
+		if err := transportError("api", tt.err); !errors.Is(err, tt.want) {
+			t.Errorf("%s: transportError = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if _, ok := transportError("api", context.Canceled).(*BackendError); ok {
+		t.Error("cancellation was wrapped as a backend error")
+	}
+}
+
+func TestRetryAfterJoined(t *testing.T) {
+	err := errors.Join(
+		&BackendError{Backend: "a", RetryAfter: time.Second, Err: ErrRateLimited},
+		&BackendError{Backend: "b", RetryAfter: time.Minute, Err: ErrBlocked},
+	)
+	if got := RetryAfter(err); got != time.Minute {
+		t.Errorf("RetryAfter = %v, want the longest wait of 1m", got)
+	}
+}
@@ -0,0 +1,307 @@
+package searcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrBinaryContent is returned for images, archives and other documents
+// without text to extract
+var ErrBinaryContent = errors.New("binary content")
+
+// pageKind is the kind of a fetched document, it picks the extractor
+type pageKind int
+
+const (
+	kindUnknown pageKind = iota
+	kindHTML
+	kindText
+	kindJSON
+	kindXML
+	kindPDF
+	kindBinary
+)
+
+// feedSummaryLimit keeps feed entries short, a feed lists many of them
+const feedSummaryLimit = 300
+
+// mediaKind returns the kind of a document from its Content-Type header,
+// kindUnknown for missing and generic types
+func mediaKind(contentType string) pageKind {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return kindUnknown
+	}
+	switch {
+	case mediaType == "text/html", mediaType == "application/xhtml+xml":
+		return kindHTML
+	case mediaType == "application/pdf", mediaType == "application/x-pdf":
+		return kindPDF
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return kindJSON
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "font/"):
+		return kindBinary
+	case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+		return kindXML
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/javascript":
+		return kindText
+	case mediaType == "application/zip", mediaType == "application/gzip",
+		mediaType == "application/x-gzip", mediaType == "application/x-tar",
+		mediaType == "application/x-7z-compressed", mediaType == "application/vnd.rar",
+		mediaType == "application/x-rar-compressed", mediaType == "application/x-msdownload",
+		mediaType == "application/wasm", strings.HasPrefix(mediaType, "application/vnd."):
+		return kindBinary
+	default:
+		return kindUnknown
+	}
+}
+
+// contentKind returns the kind of a document, sniffing the body when
+// the header doesn't tell
+func contentKind(contentType string, body []byte) pageKind {
+	if kind := mediaKind(contentType); kind != kindUnknown {
+		return kind
+	}
+	if bytes.HasPrefix(body, []byte("%PDF-")) {
+		return kindPDF
+	}
+	sniffed := http.DetectContentType(body)
+	if kind := mediaKind(sniffed); kind != kindUnknown && kind != kindText {
+		return kind
+	}
+	if !strings.HasPrefix(sniffed, "text/") {
+		return kindBinary
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return kindJSON
+	}
+	return kindText
+}
+
+// extractDocument turns a fetched document into content of the given
+// format with the extractor for its kind. HTML pages also tell about themselves.
+func extractDocument(body []byte, contentType, pageURL string, mode ExtractMode, format ContentFormat) (string, pageMeta, error) {
+	switch contentKind(contentType, body) {
+	case kindBinary:
+		return "", pageMeta{}, ErrBinaryContent
+	case kindPDF:
+		text := extractPDFText(body)
+		if text == "" {
+			// Scanned pages and fonts without a usable encoding
+			return "", pageMeta{}, ErrBinaryContent
+		}
+		return renderPlain(text, format), pageMeta{}, nil
+	case kindText:
+		return renderPlain(string(decodeBody(body, contentType)), format), pageMeta{}, nil
+	case kindJSON:
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, bytes.TrimSpace(body), "", "  "); err != nil {
+			return renderPlain(string(decodeBody(body, contentType)), format), pageMeta{}, nil
+		}
+		return renderCode(pretty.String(), "json", format), pageMeta{}, nil
+	case kindXML:
+		body = decodeXMLBody(body, contentType)
+		if feed := parseFeed(body); feed != "" {
+			// Feeds are rendered like a page listing their entries
+			return extractTextFromHTML(feed, pageURL, ExtractPage, format), pageMeta{}, nil
+		}
+		return renderPlain(xmlOutline(body), format), pageMeta{}, nil
+	default:
+		content, meta := extractHTML(string(decodeBody(body, contentType)), pageURL, mode, format)
+		return content, meta, nil
+	}
+}
+
+// renderPlain returns text with its lines kept, escaped into a
+// preformatted block for html-clean
+func renderPlain(text string, format ContentFormat) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			// runs of blank lines become a single one
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		kept = append(kept, line)
+	}
+	text = strings.Trim(strings.Join(kept, "\n"), "\n")
+	if format == FormatHTMLClean {
+		return "<pre>" + html.EscapeString(text) + "</pre>"
+	}
+	return text
+}
+
+// renderCode returns code in a fenced block for markdown and a
+// preformatted block for html-clean
+func renderCode(code, lang string, format ContentFormat) string {
+	switch format {
+	case FormatMarkdown:
+		return "```" + lang + "\n" + code + "\n```"
+	case FormatHTMLClean:
+		return fmt.Sprintf("<pre class=\"language-%s\">%s</pre>", lang, html.EscapeString(code))
+	default:
+		return code
+	}
+}
+
+// feedLink is an atom link, RSS links are plain text
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// feedEntry is an RSS item or an atom entry
+type feedEntry struct {
+	Title       string     `xml:"title"`
+	Links       []feedLink `xml:"link"`
+	PubDate     string     `xml:"pubDate"`
+	Date        string     `xml:"date"`
+	Published   string     `xml:"published"`
+	Updated     string     `xml:"updated"`
+	Description string     `xml:"description"`
+	Summary     string     `xml:"summary"`
+	Content     string     `xml:"content"`
+}
+
+// xmlFeed covers RSS 2.0, RSS 1.0 (RDF) and atom
+type xmlFeed struct {
+	XMLName xml.Name
+	Title   string `xml:"title"`
+	Channel struct {
+		Title       string      `xml:"title"`
+		Description string      `xml:"description"`
+		Items       []feedEntry `xml:"item"`
+	} `xml:"channel"`
+	Items   []feedEntry `xml:"item"`
+	Entries []feedEntry `xml:"entry"`
+}
+
+// parseFeed renders an RSS or atom feed as HTML with a section per entry,
+// an empty string for other XML documents
+func parseFeed(body []byte) string {
+	var feed xmlFeed
+	if err := newXMLDecoder(body).Decode(&feed); err != nil {
+		return ""
+	}
+	var title string
+	var entries []feedEntry
+	switch feed.XMLName.Local {
+	case "rss":
+		title = feed.Channel.Title
+		entries = feed.Channel.Items
+	case "RDF":
+		title = feed.Channel.Title
+		entries = feed.Items
+	case "feed":
+		title = feed.Title
+		entries = feed.Entries
+	default:
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<html><body>")
+	if title = strings.TrimSpace(title); title != "" {
+		fmt.Fprintf(&sb, "<h1>%s</h1>", html.EscapeString(title))
+	}
+	for _, entry := range entries {
+		sb.WriteString("<section><h2>")
+		link := entry.link()
+		if link != "" {
+			fmt.Fprintf(&sb, "<a href=\"%s\">%s</a>", html.EscapeString(link), html.EscapeString(strings.TrimSpace(entry.Title)))
+		} else {
+			sb.WriteString(html.EscapeString(strings.TrimSpace(entry.Title)))
+		}
+		sb.WriteString("</h2>")
+		if date := entry.date(); date != "" {
+			fmt.Fprintf(&sb, "<p>%s</p>", html.EscapeString(date))
+		}
+		if summary := entry.summary(); summary != "" {
+			fmt.Fprintf(&sb, "<p>%s</p>", html.EscapeString(summary))
+		}
+		sb.WriteString("</section>")
+	}
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
+
+// link returns the entry's page, atom entries may link more than one
+func (e feedEntry) link() string {
+	for _, l := range e.Links {
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return strings.TrimSpace(l.Href)
+		}
+		if text := strings.TrimSpace(l.Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+func (e feedEntry) date() string {
+	for _, d := range []string{e.PubDate, e.Published, e.Date, e.Updated} {
+		if d = strings.TrimSpace(d); d != "" {
+			return d
+		}
+	}
+	return ""
+}
+
+// summary returns the entry's text without markup, shortened
+func (e feedEntry) summary() string {
+	for _, s := range []string{e.Description, e.Summary, e.Content} {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		// Descriptions are usually escaped HTML
+		text := extractTextFromHTML(s, "", ExtractPage, FormatText)
+		return truncateUTF8(text, feedSummaryLimit)
+	}
+	return ""
+}
+
+// xmlOutline lists the text of an XML document's elements, one element
+// per line and indented by depth
+func xmlOutline(body []byte) string {
+	decoder := newXMLDecoder(body)
+	decoder.Strict = false
+	var sb strings.Builder
+	depth := 0
+	var name string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			name = t.Name.Local
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text := normalizeSpace(string(t))
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, "%s%s: %s\n", strings.Repeat("  ", max(depth-2, 0)), name, text)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrNoBackend is returned by a fallback chain without searchers
+var ErrNoBackend = errors.New("no search backend configured")
+
+// FallbackSearcher tries searchers in order and returns the first page
+// that has results. A searcher is skipped when it fails, returns nothing
+// or got blocked by its backend. When none has results the search fails
+// only if every searcher did, else it gives an empty page.
+type FallbackSearcher struct {
+	searchers []NamedSearcher
+}
+
+// NewFallbackSearcher creates a fallback chain over the given searchers
+func NewFallbackSearcher(searchers []NamedSearcher) *FallbackSearcher {
+	return &FallbackSearcher{searchers: searchers}
+}
+
+// Search returns results of the first searcher of the chain that has any.
+// Following pages stay on the searcher that served the first one,
+// the cursor remembers it.
+func (f *FallbackSearcher) Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	if len(f.searchers) == 0 {
+		return nil, ErrNoBackend
+	}
+	if opts.Cursor != "" {
+		return f.continueSearch(ctx, query, limit, opts)
+	}
+	var errs []error
+	var empty *ResultPage
+	var emptyName string
+	for _, ns := range f.searchers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := ns.Searcher.Search(ctx, query, limit, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ns.Name, err))
+			continue
+		}
+		if page.empty() {
+			if empty == nil {
+				empty, emptyName = page, ns.Name
+			}
+			continue
+		}
+		return f.wrapPage(ns.Name, page), nil
+	}
+	if empty != nil {
+		return f.wrapPage(emptyName, empty), nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// continueSearch fetches a following page from the searcher named in the cursor
+func (f *FallbackSearcher) continueSearch(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	name := cur.Get("backend")
+	for _, ns := range f.searchers {
+		if ns.Name != name {
+			continue
+		}
+		opts.Cursor = cur.Get("cursor")
+		page, err := ns.Searcher.Search(ctx, query, limit, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ns.Name, err)
+		}
+		return f.wrapPage(ns.Name, page), nil
+	}
+	return nil, ErrInvalidCursor
+}
+
+// wrapPage tags the page with the serving searcher and
+// wraps its cursor so the next page goes to the same one
+func (f *FallbackSearcher) wrapPage(name string, page *ResultPage) *ResultPage {
+	if page.Backend == "" {
+		page.Backend = name
+	}
+	if page.NextCursor != "" {
+		page.NextCursor = encodeCursor(url.Values{
+			"backend": {name},
+			"cursor":  {page.NextCursor},
+		})
+	}
+	return page
+}
@@ -0,0 +1,68 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestFallbackSearcher(t *testing.T) {
+	failed := &listSearcher{err: ErrRateLimited}
+	empty := &listSearcher{}
+	found := &listSearcher{urls: []string{"https://a.com", "https://b.com", "https://c.com"}}
+	tests := []struct {
+		name        string
+		chain       []NamedSearcher
+		wantBackend string
+		wantURLs    []string
+		wantErr     error
+	}{
+		{"first with results", []NamedSearcher{{"found", found}, {"empty", empty}}, "found", []string{"https://a.com", "https://b.com"}, nil},
+		{"skips failed", []NamedSearcher{{"failed", failed}, {"found", found}}, "found", []string{"https://a.com", "https://b.com"}, nil},
+		{"skips empty", []NamedSearcher{{"empty", empty}, {"found", found}}, "found", []string{"https://a.com", "https://b.com"}, nil},
+		{"empty beats failed", []NamedSearcher{{"failed", failed}, {"empty", empty}}, "empty", nil, nil},
+		{"every searcher failed", []NamedSearcher{{"failed", failed}}, "", nil, ErrRateLimited},
+		{"no searchers", nil, "", nil, ErrNoBackend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := NewFallbackSearcher(tt.chain).Search(context.Background(), "q", 2, SearchOptions{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Search = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if page.Backend != tt.wantBackend {
+				t.Errorf("backend = %q, want %q", page.Backend, tt.wantBackend)
+			}
+			if got := resultURLs(page.Results); !slices.Equal(got, tt.wantURLs) {
+				t.Errorf("results = %v, want %v", got, tt.wantURLs)
+			}
+		})
+	}
+}
+
+func TestFallbackSearcherPaging(t *testing.T) {
+	found := &listSearcher{urls: []string{"https://a.com", "https://b.com", "https://c.com"}}
+	f := NewFallbackSearcher([]NamedSearcher{{"empty", &listSearcher{}}, {"found", found}})
+	page, err := f.Search(context.Background(), "q", 2, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	page, err = f.Search(context.Background(), "q", 2, SearchOptions{Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("Search of the next page: %v", err)
+	}
+	if got := resultURLs(page.Results); !slices.Equal(got, []string{"https://c.com"}) || page.Backend != "found" || page.NextCursor != "" {
+		t.Errorf("next page = %v from %q with cursor %q, want [https://c.com] from found and no cursor", got, page.Backend, page.NextCursor)
+	}
+	if _, err := f.Search(context.Background(), "q", 2, SearchOptions{Cursor: encodeCursor(url.Values{"backend": {"gone"}})}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Search with a cursor of an unknown searcher = %v, want ErrInvalidCursor", err)
+	}
+}
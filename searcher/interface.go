@@ -2,15 +2,12 @@ package searcher
 
 import (
 	"context"
-	"fmt"
+	"time"
 )
 
-// SearcherType represents the type of searcher to use
-type SearcherType string
-
 const (
-	SearcherTypeScraper SearcherType = "SearcherTypeScraper"
-	SearcherTypeAPI     SearcherType = "SearcherTypeAPI"
+	defaultScraperURL = "https://html.duckduckgo.com/html/?q="
+	defaultSearXURL   = "https://searx.grailfinder.net/"
 )
 
 // SearchResult represents the content of a webpage
@@ -18,29 +15,110 @@ type SearchResult struct {
 	URL     string `json:"url"`
 	Title   string `json:"title"`
 	Content string `json:"content"`
+	// Snippet is the summary shown by the search backend, Content
+	// replaces it with the fetched page where possible
+	Snippet string `json:"snippet,omitempty"`
+	// Engines found the result, several for merged results
+	Engines       []string   `json:"engines,omitempty"`
+	PublishedDate *time.Time `json:"published_date,omitempty"`
+	Author        string     `json:"author,omitempty"`
+	SiteName      string     `json:"site_name,omitempty"`
+	// Source is the outlet of a news result
+	Source       string `json:"source,omitempty"`
+	Language     string `json:"language,omitempty"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	// FinalURL is where the page fetch ended after redirects
+	FinalURL string `json:"final_url,omitempty"`
+	// ContentLength is the size of the fetched page in bytes
+	ContentLength int64 `json:"content_length,omitempty"`
+	// FetchStatus is the response code of the page fetch, zero when the
+	// page wasn't fetched
+	FetchStatus int `json:"fetch_status,omitempty"`
+	// Metadata is the structured data of the fetched page
+	Metadata *PageMetadata `json:"metadata,omitempty"`
+	// Flags note why content is incomplete, see the Flag constants
+	Flags []string `json:"flags,omitempty"`
+}
+
+// Result flags
+const (
+	// FlagRobotsDisallowed: robots.txt forbids fetching the page,
+	// content is the search snippet
+	FlagRobotsDisallowed = "robots_disallowed"
+	// FlagBinaryContent: the page is an image, archive or other document
+	// without text to extract, content is the search snippet
+	FlagBinaryContent = "binary_content"
+	// FlagTruncated: the page was longer than the body limit,
+	// content comes from its beginning
+	FlagTruncated = "truncated"
+)
+
+// ResultPage is one batch of results returned by a searcher
+type ResultPage struct {
+	Results []SearchResult
+	// NextCursor continues the search after these results,
+	// empty when the backend has nothing more
+	NextCursor string
+	// Backend names the searcher that served the results, set by searchers
+	// combining others
+	Backend string
+	// Cached is set when the page came from the result cache
+	Cached bool
+	// Answers, infoboxes, suggestions and corrections are hints of backends
+	// that give them, SearXNG does, on the first page of a search
+	Answers     []Answer
+	Infoboxes   []Infobox
+	Suggestions []string
+	// Corrections are "did you mean" spellings of the query
+	Corrections []string
+	// UnresponsiveEngines failed to answer the backend
+	UnresponsiveEngines []UnresponsiveEngine
+}
+
+// empty reports whether the page has neither results nor answers,
+// an answer is a result of its own
+func (p *ResultPage) empty() bool {
+	return len(p.Results) == 0 && len(p.Answers) == 0 && len(p.Infoboxes) == 0
+}
+
+// Answer is a direct answer to the query, e.g. a conversion or a definition
+type Answer struct {
+	Text   string `json:"text"`
+	URL    string `json:"url,omitempty"`
+	Engine string `json:"engine,omitempty"`
+}
+
+// Infobox summarizes the entity the query is about, e.g. from Wikipedia
+type Infobox struct {
+	Title      string             `json:"title"`
+	Content    string             `json:"content,omitempty"`
+	URL        string             `json:"url,omitempty"`
+	ImageURL   string             `json:"image_url,omitempty"`
+	Attributes []InfoboxAttribute `json:"attributes,omitempty"`
+	Links      []InfoboxLink      `json:"links,omitempty"`
+	Engines    []string           `json:"engines,omitempty"`
+}
+
+// InfoboxAttribute is a fact of an infobox, e.g. "Population: 3.6 million"
+type InfoboxAttribute struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// InfoboxLink points to more about the entity, e.g. its official website
+type InfoboxLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// UnresponsiveEngine is an engine of a metasearch backend that failed
+type UnresponsiveEngine struct {
+	Engine string `json:"engine"`
+	Error  string `json:"error"`
 }
 
-// Searcher defines the interface for different search implementations
+// Searcher defines the interface for different search implementations.
+// opts.Cursor set to a NextCursor of a previous page continues that search.
 type Searcher interface {
-	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
-}
-
-// NewSearchService creates a new search service based on the provided type.
-// Returns an error if the type is not recognized.
-func NewSearchService(t SearcherType, url string) (Searcher, error) {
-	// url: there must be a better way
-	switch t {
-	case SearcherTypeScraper:
-		if url == "" {
-			url = "https://html.duckduckgo.com/html/?q="
-		}
-		return NewWebScraper(url), nil
-	case SearcherTypeAPI:
-		if url == "" {
-			url = "https://searx.grailfinder.net/"
-		}
-		return NewSearXNGAPISearcher(url), nil // Use config.toml for API endpoint
-	default:
-		return nil, fmt.Errorf("unknown searcher type: %s", t)
-	}
+	Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error)
 }
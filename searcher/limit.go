@@ -0,0 +1,49 @@
+package searcher
+
+import (
+	"errors"
+	"io"
+)
+
+// defaultMaxBodyBytes caps every response body we read
+const defaultMaxBodyBytes = 5 << 20
+
+// ErrBodyTooLarge is returned for responses that only make sense
+// complete, e.g. JSON, and are longer than the body limit
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// cappedReader reads at most limit bytes and notes whether the
+// underlying reader had more
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func newCappedReader(r io.Reader, limit int64) *cappedReader {
+	return &cappedReader{r: r, remaining: limit}
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		// Peek a byte to tell a body of exactly limit bytes from a longer one
+		var b [1]byte
+		if n, _ := io.ReadFull(c.r, b[:]); n > 0 {
+			c.exceeded = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// readCapped reads up to limit bytes, truncated is set when the body was longer
+func readCapped(r io.Reader, limit int64) (body []byte, truncated bool, err error) {
+	capped := newCappedReader(r, limit)
+	body, err = io.ReadAll(capped)
+	return body, capped.exceeded, err
+}
@@ -0,0 +1,717 @@
+package searcher
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// mdWriter builds Markdown line by line. Line breaks are requested
+// and only written before the next text, so blocks never leave
+// trailing blank lines and nested blocks don't pile them up.
+type mdWriter struct {
+	sb strings.Builder
+	// prefix starts every line, e.g. list indentation or '> '
+	prefix string
+	// pending counts the line breaks to write before the next text
+	pending int
+	// breakPrefix is the prefix when the pending breaks were requested
+	breakPrefix string
+	// atMarker is set right after a list marker, so the item's first block
+	// stays on the marker's line
+	atMarker bool
+	// flat joins blocks with spaces, only hard breaks around code
+	// and tables are kept
+	flat bool
+	hard bool
+}
+
+// block ends the current block with a blank line
+func (w *mdWriter) block() {
+	w.lineBreaks(2, false)
+}
+
+// newline ends the current line
+func (w *mdWriter) newline() {
+	w.lineBreaks(1, false)
+}
+
+// hardBlock and hardNewline are line breaks kept in flat mode
+func (w *mdWriter) hardBlock() {
+	w.lineBreaks(2, true)
+}
+
+func (w *mdWriter) hardNewline() {
+	w.lineBreaks(1, true)
+}
+
+func (w *mdWriter) lineBreaks(n int, hard bool) {
+	if w.atMarker {
+		return
+	}
+	if w.pending == 0 {
+		w.breakPrefix = w.prefix
+	}
+	w.pending = max(w.pending, n)
+	w.hard = w.hard || hard
+}
+
+// raw writes s as it is after pending line breaks
+func (w *mdWriter) raw(s string) {
+	if s == "" {
+		return
+	}
+	switch {
+	case w.sb.Len() == 0:
+		w.sb.WriteString(w.prefix)
+	case w.flat && !w.hard:
+		if w.pending > 0 && !strings.HasSuffix(w.sb.String(), " ") {
+			w.sb.WriteString(" ")
+		}
+	default:
+		for i := 0; i < w.pending; i++ {
+			w.sb.WriteString("\n")
+			if i < w.pending-1 {
+				// blank lines belong to the blocks on both of their sides
+				w.sb.WriteString(strings.TrimRight(commonPrefix(w.breakPrefix, w.prefix), " "))
+			} else {
+				w.sb.WriteString(w.prefix)
+			}
+		}
+	}
+	w.pending = 0
+	w.hard = false
+	w.sb.WriteString(s)
+	w.atMarker = false
+}
+
+// text writes inline text with whitespace collapsed
+func (w *mdWriter) text(s string) {
+	s = collapseSpace(s)
+	if s == "" {
+		return
+	}
+	out := w.sb.String()
+	lineStart := w.pending > 0 || w.atMarker || out == "" || strings.HasSuffix(out, "\n"+w.prefix)
+	if lineStart || strings.HasSuffix(out, " ") {
+		s = strings.TrimLeft(s, " ")
+	}
+	w.raw(s)
+}
+
+// marker starts a list item
+func (w *mdWriter) marker(m string) {
+	w.newline()
+	w.raw(m)
+	w.atMarker = true
+}
+
+func (w *mdWriter) String() string {
+	return strings.TrimSpace(w.sb.String())
+}
+
+// commonPrefix returns the longest prefix of a and b
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+var spaceRun = regexp.MustCompile(`\s+`)
+
+// collapseSpace turns whitespace runs into single spaces, keeping
+// leading and trailing ones as a space
+func collapseSpace(s string) string {
+	return spaceRun.ReplaceAllString(s, " ")
+}
+
+// mdRenderer converts a cleaned DOM to Markdown, or to plain text with
+// the same block structure when markup is off
+type mdRenderer struct {
+	w      mdWriter
+	base   *url.URL
+	markup bool
+}
+
+// renderMarkdown converts the selection to Markdown, links and images
+// are resolved against base
+func renderMarkdown(sel *goquery.Selection, base *url.URL) string {
+	r := &mdRenderer{base: base, markup: true}
+	for _, n := range sel.Nodes {
+		r.node(n)
+	}
+	return r.w.String()
+}
+
+// renderText returns the text of the selection with words of
+// neighbouring blocks kept apart. Code blocks stay verbatim and
+// tables become tab separated rows, each on lines of their own.
+func renderText(sel *goquery.Selection) string {
+	r := &mdRenderer{w: mdWriter{flat: true}}
+	for _, n := range sel.Nodes {
+		r.node(n)
+	}
+	return r.w.String()
+}
+
+func (r *mdRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.node(c)
+	}
+}
+
+func (r *mdRenderer) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.w.text(n.Data)
+		return
+	case html.DocumentNode:
+		r.children(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.w.block()
+		if r.markup {
+			level, _ := strconv.Atoi(n.Data[1:])
+			r.w.raw(strings.Repeat("#", level) + " ")
+		}
+		r.children(n)
+		r.w.block()
+	case "p", "div", "section", "article", "main", "header", "footer", "figure",
+		"figcaption", "dl", "dd", "address", "details", "summary", "tr", "caption":
+		r.w.block()
+		r.children(n)
+		r.w.block()
+	case "dt", "td", "th":
+		r.w.newline()
+		r.children(n)
+		r.w.newline()
+	case "br":
+		r.w.newline()
+	case "hr":
+		r.w.block()
+		if r.markup {
+			r.w.raw("---")
+		}
+		r.w.block()
+	case "ul", "ol":
+		r.list(n)
+	case "li":
+		// an item outside of a list
+		r.w.block()
+		r.children(n)
+		r.w.block()
+	case "pre":
+		r.pre(n)
+	case "table":
+		r.table(n)
+	case "blockquote":
+		r.w.block()
+		prefix := r.w.prefix
+		if r.markup {
+			r.w.prefix += "> "
+		}
+		r.children(n)
+		r.w.prefix = prefix
+		r.w.block()
+	case "code", "kbd", "samp":
+		r.wrap(n, "`")
+	case "strong", "b":
+		r.wrap(n, "**")
+	case "em", "i":
+		r.wrap(n, "_")
+	case "a":
+		r.link(n)
+	case "img":
+		r.image(n)
+	default:
+		r.children(n)
+	}
+}
+
+// wrap renders inline children between marks, e.g. '**bold**'
+func (r *mdRenderer) wrap(n *html.Node, mark string) {
+	text := collapseSpace(nodeText(n))
+	if !r.markup || strings.TrimSpace(text) == "" {
+		r.children(n)
+		return
+	}
+	if strings.HasPrefix(text, " ") {
+		r.w.text(" ")
+	}
+	r.w.text(mark + strings.TrimSpace(text) + mark)
+	if strings.HasSuffix(text, " ") {
+		r.w.text(" ")
+	}
+}
+
+func (r *mdRenderer) link(n *html.Node) {
+	href := r.resolve(nodeAttr(n, "href"))
+	if !r.markup || href == "" || strings.HasPrefix(href, "javascript:") {
+		r.children(n)
+		return
+	}
+	text := strings.TrimSpace(collapseSpace(nodeText(n)))
+	if text == "" {
+		return
+	}
+	r.w.text(" [" + text + "](" + href + ")")
+}
+
+func (r *mdRenderer) image(n *html.Node) {
+	if !r.markup {
+		return
+	}
+	src := r.resolve(nodeAttr(n, "src"))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return
+	}
+	r.w.text(" ![" + strings.TrimSpace(nodeAttr(n, "alt")) + "](" + src + ")")
+}
+
+// list renders list items with markers, nested content is indented
+// below the marker
+func (r *mdRenderer) list(n *html.Node) {
+	ordered := n.Data == "ol"
+	num := 1
+	if start, err := strconv.Atoi(nodeAttr(n, "start")); err == nil {
+		num = start
+	}
+	prefix := r.w.prefix
+	if r.w.atMarker || prefix != "" && strings.TrimSpace(prefix) == "" {
+		// a nested list follows its item's text directly
+		r.w.newline()
+	} else {
+		r.w.block()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			if c.Type == html.ElementNode {
+				r.node(c)
+			}
+			continue
+		}
+		m := "- "
+		if ordered {
+			m = fmt.Sprintf("%d. ", num)
+			num++
+		}
+		if !r.markup {
+			m = ""
+		}
+		r.w.marker(m)
+		r.w.prefix = prefix + strings.Repeat(" ", len(m))
+		r.children(c)
+		r.w.prefix = prefix
+		r.w.newline()
+	}
+	r.w.block()
+}
+
+// pre keeps preformatted text verbatim, in a fenced block with the
+// language of the code when the page tells it
+func (r *mdRenderer) pre(n *html.Node) {
+	code := strings.Trim(nodeText(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return
+	}
+	r.w.hardBlock()
+	fence := ""
+	if r.markup {
+		// The fence has to be longer than any backtick run in the code
+		fence = "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		r.w.raw(fence + codeLanguage(n))
+		r.w.hardNewline()
+	}
+	for i, line := range strings.Split(code, "\n") {
+		if i > 0 {
+			r.w.hardNewline()
+		}
+		if strings.TrimSpace(line) == "" {
+			// keep blank lines of the code
+			r.w.pending++
+			continue
+		}
+		r.w.raw(strings.TrimRight(line, " \t\r"))
+	}
+	if r.markup {
+		r.w.hardNewline()
+		r.w.raw(fence)
+	}
+	r.w.hardBlock()
+}
+
+// languageClass matches the class names highlighters use to name the
+// language, e.g. 'language-go', 'lang-py', 'highlight-source-js' or 'brush: js'
+var languageClass = regexp.MustCompile(`(?i)(?:^|\s)(?:language|lang|highlight-source|highlight|brush|sourceCode)[-:]\s*([a-z0-9_+#.-]+)`)
+
+// codeLanguage returns the language of a code block from the classes
+// or data-lang of the block, its code element or its wrapper
+func codeLanguage(pre *html.Node) string {
+	candidates := []*html.Node{pre}
+	for c := pre.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "code" {
+			candidates = append(candidates, c)
+		}
+	}
+	if pre.Parent != nil {
+		candidates = append(candidates, pre.Parent)
+	}
+	for _, n := range candidates {
+		if lang := nodeAttr(n, "data-lang"); lang != "" {
+			return strings.ToLower(lang)
+		}
+		if m := languageClass.FindStringSubmatch(nodeAttr(n, "class")); m != nil {
+			switch lang := strings.ToLower(m[1]); lang {
+			case "none", "plaintext", "nohighlight":
+			default:
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// table renders a data table as a Markdown grid, or as tab separated rows
+// in plain text. Layout tables, those nesting tables or with a single
+// column, are rendered as blocks.
+func (r *mdRenderer) table(n *html.Node) {
+	rows, caption := tableRows(n)
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns < 2 {
+		r.w.block()
+		r.children(n)
+		r.w.block()
+		return
+	}
+	if caption != "" {
+		r.w.block()
+		r.w.text(caption)
+	}
+	r.w.hardBlock()
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		if i > 0 {
+			r.w.hardNewline()
+		}
+		if !r.markup {
+			r.w.raw(strings.Join(row, "\t"))
+			continue
+		}
+		for j, cell := range row {
+			row[j] = strings.ReplaceAll(cell, "|", "\\|")
+		}
+		r.w.raw("| " + strings.Join(row, " | ") + " |")
+		if i == 0 {
+			// Markdown needs a header, the first row serves as one
+			r.w.hardNewline()
+			r.w.raw("|" + strings.Repeat(" --- |", columns))
+		}
+	}
+	r.w.hardBlock()
+}
+
+// tableRows returns the cell texts of a table's rows and its caption,
+// nil rows for tables that nest other tables
+func tableRows(table *html.Node) ([][]string, string) {
+	var rows [][]string
+	var caption string
+	nested := false
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			case "caption":
+				caption = normalizeSpace(nodeText(c))
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+						continue
+					}
+					if hasDescendant(cell, "table") {
+						nested = true
+					}
+					row = append(row, normalizeSpace(nodeText(cell)))
+					// Spanned columns get empty cells to keep the grid aligned
+					span, _ := strconv.Atoi(nodeAttr(cell, "colspan"))
+					for i := 1; i < min(span, 50); i++ {
+						row = append(row, "")
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	walk(table)
+	if nested {
+		return nil, caption
+	}
+	return rows, caption
+}
+
+// hasDescendant reports whether an element with the tag is below n
+func hasDescendant(n *html.Node, tag string) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag || hasDescendant(c, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve makes a link absolute
+func (r *mdRenderer) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || r.base == nil {
+		return ref
+	}
+	u, err := r.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// nodeText returns the raw text below the node
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			sb.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			sb.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// nodeAttr returns the value of the attribute or an empty string
+func nodeAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// keptAttrs are the attributes left by renderCleanHTML
+var keptAttrs = map[string]bool{
+	"href": true, "src": true, "alt": true, "title": true,
+	"start": true, "colspan": true, "rowspan": true,
+}
+
+// renderCleanHTML returns the selection's HTML with only structural
+// attributes kept and links made absolute
+func renderCleanHTML(sel *goquery.Selection, base *url.URL) string {
+	r := &mdRenderer{base: base}
+	var buf bytes.Buffer
+	for _, n := range sel.Nodes {
+		clone := cloneNode(n)
+		// the language hint of code blocks survives as a class, it is
+		// read before the wrappers lose their attributes
+		langs := make(map[*html.Node]string)
+		var findCode func(*html.Node)
+		findCode = func(n *html.Node) {
+			if n.Type == html.ElementNode && n.Data == "pre" {
+				langs[n] = codeLanguage(n)
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				findCode(c)
+			}
+		}
+		findCode(clone)
+		var strip func(n *html.Node, inPre bool)
+		strip = func(n *html.Node, inPre bool) {
+			if n.Type == html.TextNode && !inPre && strings.TrimSpace(n.Data) == "" && strings.Contains(n.Data, "\n") {
+				// whitespace left by removed elements becomes a single line break
+				n.Data = "\n"
+				if prev := n.PrevSibling; prev != nil && prev.Type == html.TextNode && prev.Data == "\n" {
+					n.Data = ""
+				}
+			}
+			if n.Type == html.ElementNode {
+				if n.Data == "pre" {
+					inPre = true
+				}
+				attrs := n.Attr[:0]
+				for _, attr := range n.Attr {
+					if !keptAttrs[attr.Key] {
+						continue
+					}
+					if attr.Key == "href" || attr.Key == "src" {
+						attr.Val = r.resolve(attr.Val)
+					}
+					attrs = append(attrs, attr)
+				}
+				if lang := langs[n]; lang != "" {
+					attrs = append(attrs, html.Attribute{Key: "class", Val: "language-" + lang})
+				}
+				n.Attr = attrs
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				strip(c, inPre)
+			}
+		}
+		strip(clone, false)
+		if err := html.Render(&buf, clone); err != nil {
+			return ""
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// cloneNode deep copies a node, so cleaning it leaves the document alone
+func cloneNode(n *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		clone.AppendChild(cloneNode(c))
+	}
+	return clone
+}
+
+// truncateContent cuts content to at most limit bytes. Markdown is cut
+// between blocks and HTML after an element, so neither comes out broken.
+func truncateContent(content string, limit int, format ContentFormat) string {
+	switch format {
+	case FormatMarkdown:
+		return truncateMarkdown(content, limit)
+	case FormatHTMLClean:
+		return truncateHTML(content, limit)
+	default:
+		return truncateUTF8(content, limit)
+	}
+}
+
+// fenceClose ends a code block left open by a cut
+const fenceClose = "\n```"
+
+// truncateMarkdown cuts after the last block that fits, inside a code
+// block or an overlong block after the last line, and closes a code
+// block left open
+func truncateMarkdown(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	// A block ends where a blank line starts, it fits if it ends within limit
+	if i := strings.LastIndex(s[:min(len(s), limit+2)], "\n\n"); i > 0 && !inFence(s[:i]) {
+		return s[:i]
+	}
+	cut := truncateUTF8(s, limit)
+	if inFence(cut) {
+		cut = truncateUTF8(s, limit-len(fenceClose))
+	}
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	if inFence(cut) {
+		cut += fenceClose
+	}
+	return cut
+}
+
+// inFence reports whether markdown ends inside a fenced code block
+func inFence(s string) bool {
+	open := false
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimLeft(line, " >"), "```") {
+			open = !open
+		}
+	}
+	return open
+}
+
+// voidElements have no end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// truncateHTML cuts HTML after the last end tag or text that fits together
+// with the end tags of the elements still open there, and adds those
+func truncateHTML(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	z := html.NewTokenizer(strings.NewReader(s))
+	var open []string
+	var cutOpen []string
+	offset, cut := 0, 0
+	closing := 0 // length of the end tags of open elements
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		offset += len(z.Raw())
+		switch tt {
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if !voidElements[string(name)] {
+				open = append(open, string(name))
+				closing += len(name) + 3
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if len(open) > 0 && open[len(open)-1] == string(name) {
+				open = open[:len(open)-1]
+				closing -= len(name) + 3
+			}
+		}
+		if offset+closing > limit {
+			break
+		}
+		// Cutting after a start tag would leave an empty element
+		if tt != html.StartTagToken {
+			cut = offset
+			cutOpen = append(cutOpen[:0], open...)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString(s[:cut])
+	for i := len(cutOpen) - 1; i >= 0; i-- {
+		sb.WriteString("</" + cutOpen[i] + ">")
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package searcher
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// renderBody parses the fragment and renders its body with render
+func renderBody(t *testing.T, fragment string, render func(*goquery.Selection, *url.URL) string) string {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/docs/")
+	return render(doc.Find("body"), base)
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"heading and inline markup", `<h1>Title</h1><p>Some <strong>bold</strong> and <em>it</em> text.</p>`, "# Title\n\nSome **bold** and _it_ text."},
+		{"links resolve", `<p><a href="page">a link</a> and <a href="https://go.dev/">another</a></p>`, "[a link](https://example.com/docs/page) and [another](https://go.dev/)"},
+		{"images resolve", `<img src="/i.png" alt="pic">`, "![pic](https://example.com/i.png)"},
+		{"nested lists", `<ul><li>one</li><li>two<ul><li>nested</li></ul></li></ul><ol><li>first</li><li>second</li></ol>`, "- one\n- two\n  - nested\n\n1. first\n2. second"},
+		{"blockquote", `<blockquote><p>quoted</p></blockquote><hr>`, "> quoted\n\n---"},
+		{"inline code", `<p>Use <code>go test</code></p>`, "Use `go test`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderBody(t, tt.html, renderMarkdown); got != tt.want {
+				t.Errorf("renderMarkdown = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int
+		format  ContentFormat
+		want    string
+	}{
+		{"short markdown", "short", 100, FormatMarkdown, "short"},
+		{"markdown at a block", "# Title\n\nFirst paragraph.\n\nSecond paragraph is longer.", 30, FormatMarkdown, "# Title\n\nFirst paragraph."},
+		{"markdown code block closed", "```go\nline one\nline two\nline three\n```", 30, FormatMarkdown, "```go\nline one\nline two\n```"},
+		{"markdown at a line", "> quote line one\n> quote line two", 20, FormatMarkdown, "> quote line one"},
+		{"markdown overlong line", "one long line without breaks at all", 20, FormatMarkdown, "one long line withou"},
+		{"html after an element", "<p>First</p><p>Second paragraph</p>", 25, FormatHTMLClean, "<p>First</p>"},
+		{"html closes open elements", "<ul><li>one</li><li>two</li><li>three</li></ul>", 30, FormatHTMLClean, "<ul><li>one</li></ul>"},
+		{"html void elements", `<p>x<br/>y<img src="a"/></p>`, 22, FormatHTMLClean, "<p>x<br/>y</p>"},
+		{"text keeps runes whole", "héllo wörld", 2, FormatText, "h"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateContent(tt.content, tt.limit, tt.format)
+			if got != tt.want {
+				t.Errorf("truncateContent = %q, want %q", got, tt.want)
+			}
+			if len(got) > tt.limit {
+				t.Errorf("truncateContent gave %d bytes, over the limit of %d", len(got), tt.limit)
+			}
+		})
+	}
+}
+
+func TestRenderCodeAndTables(t *testing.T) {
+	code := "<p>Run it:</p><pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"hi\")\n}</code></pre>"
+	table := `<table><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td>1</td></tr></table>`
+	tests := []struct {
+		name   string
+		html   string
+		render func(*goquery.Selection, *url.URL) string
+		want   string
+	}{
+		{"markdown code block", code, renderMarkdown, "Run it:\n\n```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```"},
+		{"markdown plain pre", `<pre>plain *not* markdown</pre>`, renderMarkdown, "```\nplain *not* markdown\n```"},
+		{"text code block", code, textRenderer, "Run it:\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}"},
+		{"html-clean code block", code, renderCleanHTML, "<body><p>Run it:</p><pre class=\"language-go\"><code>func main() {\n\tfmt.Println(&#34;hi&#34;)\n}</code></pre></body>"},
+		{"markdown table", table, renderMarkdown, "| Name | Value |\n| --- | --- |\n| a\\|b | 1 |"},
+		{"markdown table without header cells", `<table><tr><td>x</td><td>y</td></tr><tr><td>1</td><td>2</td></tr></table>`, renderMarkdown, "| x | y |\n| --- | --- |\n| 1 | 2 |"},
+		{"text table", table, textRenderer, "Name\tValue\na|b\t1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderBody(t, tt.html, tt.render); got != tt.want {
+				t.Errorf("rendered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// textRenderer renders text with the signature of the other renderers
+func textRenderer(sel *goquery.Selection, _ *url.URL) string {
+	return renderText(sel)
+}
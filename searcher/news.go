@@ -0,0 +1,200 @@
+package searcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// CategoryNews is the news vertical, both backends know it
+const CategoryNews = "news"
+
+// duckDuckGoNewsURL serves the token page and news.js of DuckDuckGo's
+// news vertical, the html endpoint has none
+const duckDuckGoNewsURL = "https://duckduckgo.com/"
+
+// vqdPattern finds the token DuckDuckGo requires for its json endpoints
+// in the page of a query
+var vqdPattern = regexp.MustCompile(`vqd=["']?([0-9-]+)`)
+
+// duckDuckGoNewsResult is a result of news.js
+type duckDuckGoNewsResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Excerpt string `json:"excerpt"`
+	Source  string `json:"source"`
+	// Date is a unix timestamp
+	Date int64 `json:"date"`
+}
+
+// duckDuckGoNewsResponse is a page of news.js, Next is empty on the last one
+type duckDuckGoNewsResponse struct {
+	Results []duckDuckGoNewsResult `json:"results"`
+	Next    string                 `json:"next"`
+}
+
+// searchNews searches DuckDuckGo's news vertical. The cursor carries
+// the token of the query and the offset of the next page.
+func (ws *WebScraper) searchNews(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	skip, err := cursorInt(cur, cursorSkip, 0)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := cursorInt(cur, "s", 0)
+	if err != nil {
+		return nil, err
+	}
+	vqd := cur.Get("vqd")
+	if vqd == "" {
+		if vqd, err = ws.duckDuckGoToken(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("vqd", vqd)
+	params.Set("o", "json")
+	params.Set("noamp", "1")
+	params.Set("l", "wt-wt")
+	if kl := opts.duckDuckGoLocale(); kl != "" {
+		params.Set("l", kl)
+	}
+	if df := opts.duckDuckGoTime(); df != "" {
+		params.Set("df", df)
+	}
+	if kp := opts.duckDuckGoSafeSearch(); kp != "" {
+		params.Set("p", kp)
+	}
+	if offset > 0 {
+		params.Set("s", strconv.Itoa(offset))
+	}
+	body, err := ws.getDuckDuckGo(ctx, ws.newsURL+"news.js?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var news duckDuckGoNewsResponse
+	if err := json.Unmarshal(body, &news); err != nil {
+		return nil, &BackendError{Backend: duckDuckGoBackend, Err: fmt.Errorf("%w: %w", ErrInvalidResponse, err)}
+	}
+	valid := make([]SearchResult, 0, len(news.Results))
+	for _, item := range news.Results {
+		if item.Title == "" || item.URL == "" {
+			continue
+		}
+		// Excerpts highlight the query with <b> tags
+		excerpt := extractTextFromHTML(item.Excerpt, "", ExtractPage, FormatText)
+		result := SearchResult{
+			URL:     item.URL,
+			Title:   item.Title,
+			Content: excerpt,
+			Snippet: excerpt,
+			Source:  item.Source,
+		}
+		if item.Date > 0 {
+			published := time.Unix(item.Date, 0).UTC()
+			result.PublishedDate = &published
+		}
+		valid = append(valid, result)
+	}
+	taken, nextSkip, more := takePage(valid, skip, limit)
+	results := slices.Clone(taken)
+	ws.fetchContents(ctx, results, opts.Format)
+	next := url.Values{}
+	next.Set("vqd", vqd)
+	switch {
+	case more:
+		// Continue on the same page
+		next.Set("s", strconv.Itoa(offset))
+		next.Set(cursorSkip, strconv.Itoa(nextSkip))
+	case news.Next != "":
+		next.Set("s", strconv.Itoa(offset+len(news.Results)))
+	default:
+		next = nil
+	}
+	var nextCursor string
+	if len(next) > 0 {
+		nextCursor = encodeCursor(next)
+	}
+	return &ResultPage{
+		Results:    results,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// duckDuckGoToken returns the vqd token of a query, found in the page
+// DuckDuckGo serves for it
+func (ws *WebScraper) duckDuckGoToken(ctx context.Context, query string) (string, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("ia", CategoryNews)
+	body, err := ws.getDuckDuckGo(ctx, ws.newsURL+"?"+params.Encode())
+	if err != nil {
+		return "", err
+	}
+	match := vqdPattern.FindSubmatch(body)
+	if match == nil {
+		return "", &BackendError{Backend: duckDuckGoBackend, Err: fmt.Errorf("%w: no vqd token", ErrInvalidResponse)}
+	}
+	return string(match[1]), nil
+}
+
+// getDuckDuckGo requests a DuckDuckGo page and returns its body,
+// backing off like the html search when DuckDuckGo blocks us
+func (ws *WebScraper) getDuckDuckGo(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := ws.doDuckDuckGo(req)
+	return body, err
+}
+
+// NewsSearcher searches the news category of another searcher,
+// latest results first unless relevance order is asked for
+type NewsSearcher struct {
+	searcher Searcher
+}
+
+// NewNewsSearcher creates a news search over the given searcher
+func NewNewsSearcher(s Searcher) *NewsSearcher {
+	return &NewsSearcher{searcher: s}
+}
+
+func (n *NewsSearcher) Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	opts.Categories = []string{CategoryNews}
+	page, err := n.searcher.Search(ctx, query, limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Sort != SortRelevance {
+		sortByDate(page.Results)
+	}
+	return page, nil
+}
+
+// sortByDate orders results latest first, undated ones keep their
+// order after the dated ones
+func sortByDate(results []SearchResult) {
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		switch {
+		case a.PublishedDate == nil && b.PublishedDate == nil:
+			return 0
+		case a.PublishedDate == nil:
+			return 1
+		case b.PublishedDate == nil:
+			return -1
+		default:
+			return b.PublishedDate.Compare(*a.PublishedDate)
+		}
+	})
+}
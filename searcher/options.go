@@ -0,0 +1,204 @@
+package searcher
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SafeSearch is the level of adult content filtering
+type SafeSearch string
+
+const (
+	SafeSearchDefault  SafeSearch = ""
+	SafeSearchOff      SafeSearch = "off"
+	SafeSearchModerate SafeSearch = "moderate"
+	SafeSearchStrict   SafeSearch = "strict"
+)
+
+// TimeRange limits results to the given period
+type TimeRange string
+
+const (
+	TimeRangeAny   TimeRange = ""
+	TimeRangeDay   TimeRange = "day"
+	TimeRangeWeek  TimeRange = "week"
+	TimeRangeMonth TimeRange = "month"
+	TimeRangeYear  TimeRange = "year"
+)
+
+// ContentFormat is the format of fetched page content
+type ContentFormat string
+
+const (
+	// FormatText is plain text with whitespace collapsed, the default
+	FormatText ContentFormat = "text"
+	// FormatMarkdown keeps headings, lists, links and code as Markdown
+	FormatMarkdown ContentFormat = "markdown"
+	// FormatHTMLClean is the extracted HTML without scripts, styles and attributes
+	FormatHTMLClean ContentFormat = "html-clean"
+)
+
+// SortOrder is the order of results within a page
+type SortOrder string
+
+const (
+	// SortRelevance keeps the order of the backend, the default except for news
+	SortRelevance SortOrder = "relevance"
+	// SortDate puts the latest results first
+	SortDate SortOrder = "date"
+)
+
+// SearchOptions narrow down a search, zero value means backend defaults
+type SearchOptions struct {
+	// Language is an ISO 639-1 code, e.g. "en"
+	Language string `json:"language,omitempty"`
+	// Region is an ISO 3166-1 country code, e.g. "us"
+	Region     string     `json:"region,omitempty"`
+	SafeSearch SafeSearch `json:"safe_search,omitempty"`
+	TimeRange  TimeRange  `json:"time_range,omitempty"`
+	// Format of the page content, text when empty
+	Format ContentFormat `json:"content_format,omitempty"`
+	// Categories and Engines focus SearXNG on some of its result
+	// categories, e.g. "news", or engines, e.g. "github". Instances
+	// define their own, so any name is passed on.
+	Categories []string `json:"categories,omitempty"`
+	Engines    []string `json:"engines,omitempty"`
+	// Sort orders the results, empty is relevance except for news
+	Sort SortOrder `json:"sort,omitempty"`
+	// Cursor is the NextCursor of a previous page of the same search
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// Validate normalizes the options and checks that enum values are known
+func (o *SearchOptions) Validate() error {
+	o.Language = strings.ToLower(strings.TrimSpace(o.Language))
+	o.Region = strings.ToLower(strings.TrimSpace(o.Region))
+	o.SafeSearch = SafeSearch(strings.ToLower(string(o.SafeSearch)))
+	o.TimeRange = TimeRange(strings.ToLower(string(o.TimeRange)))
+	o.Format = ContentFormat(strings.ToLower(strings.TrimSpace(string(o.Format))))
+	if o.Format == "" {
+		o.Format = FormatText
+	}
+	o.Sort = SortOrder(strings.ToLower(strings.TrimSpace(string(o.Sort))))
+	o.Categories = normalizeNames(o.Categories)
+	o.Engines = normalizeNames(o.Engines)
+	switch o.SafeSearch {
+	case SafeSearchDefault, SafeSearchOff, SafeSearchModerate, SafeSearchStrict:
+	default:
+		return fmt.Errorf("unknown safe search level: %s", o.SafeSearch)
+	}
+	switch o.TimeRange {
+	case TimeRangeAny, TimeRangeDay, TimeRangeWeek, TimeRangeMonth, TimeRangeYear:
+	default:
+		return fmt.Errorf("unknown time range: %s", o.TimeRange)
+	}
+	switch o.Format {
+	case FormatText, FormatMarkdown, FormatHTMLClean:
+	default:
+		return fmt.Errorf("unknown content format: %s", o.Format)
+	}
+	switch o.Sort {
+	case "", SortRelevance, SortDate:
+	default:
+		return fmt.Errorf("unknown sort order: %s", o.Sort)
+	}
+	return nil
+}
+
+// normalizeNames lowercases and trims names, splits comma separated ones
+// and drops empty ones and repeats, nil when none are left
+func normalizeNames(names []string) []string {
+	var out []string
+	for _, name := range names {
+		for _, part := range strings.Split(name, ",") {
+			part = strings.ToLower(strings.TrimSpace(part))
+			if part != "" && !slices.Contains(out, part) {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// englishRegions are regions duckduckgo only knows with english as language
+var englishRegions = map[string]bool{
+	"us": true, "uk": true, "gb": true, "au": true, "nz": true,
+	"ie": true, "in": true, "za": true, "ph": true, "sg": true,
+}
+
+// duckDuckGoLocale returns the 'kl' parameter, e.g. "us-en" or "de-de"
+func (o SearchOptions) duckDuckGoLocale() string {
+	region := o.Region
+	if region == "gb" {
+		region = "uk"
+	}
+	switch {
+	case region == "" && o.Language == "":
+		return ""
+	case region == "":
+		// no region means worldwide results
+		return "wt-wt"
+	case o.Language != "":
+		return region + "-" + o.Language
+	case englishRegions[region]:
+		return region + "-en"
+	default:
+		return region + "-" + region
+	}
+}
+
+// duckDuckGoTime returns the 'df' parameter
+func (o SearchOptions) duckDuckGoTime() string {
+	switch o.TimeRange {
+	case TimeRangeDay:
+		return "d"
+	case TimeRangeWeek:
+		return "w"
+	case TimeRangeMonth:
+		return "m"
+	case TimeRangeYear:
+		return "y"
+	default:
+		return ""
+	}
+}
+
+// duckDuckGoSafeSearch returns the 'kp' parameter
+func (o SearchOptions) duckDuckGoSafeSearch() string {
+	switch o.SafeSearch {
+	case SafeSearchStrict:
+		return "1"
+	case SafeSearchModerate:
+		return "-1"
+	case SafeSearchOff:
+		return "-2"
+	default:
+		return ""
+	}
+}
+
+// searXLanguage returns the 'language' parameter, e.g. "en-US" or "en"
+func (o SearchOptions) searXLanguage() string {
+	if o.Language == "" {
+		return ""
+	}
+	if o.Region == "" {
+		return o.Language
+	}
+	return o.Language + "-" + strings.ToUpper(o.Region)
+}
+
+// searXSafeSearch returns the 'safesearch' parameter
+func (o SearchOptions) searXSafeSearch() string {
+	switch o.SafeSearch {
+	case SafeSearchOff:
+		return "0"
+	case SafeSearchModerate:
+		return "1"
+	case SafeSearchStrict:
+		return "2"
+	default:
+		return ""
+	}
+}
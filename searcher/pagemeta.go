@@ -0,0 +1,266 @@
+package searcher
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// pageMeta is what a page tells about itself in its head
+type pageMeta struct {
+	Author       string     `json:"author,omitempty"`
+	SiteName     string     `json:"site_name,omitempty"`
+	Language     string     `json:"language,omitempty"`
+	CanonicalURL string     `json:"canonical_url,omitempty"`
+	Published    *time.Time `json:"published,omitempty"`
+	// Data is the page's structured data, nil when it declares none
+	Data *PageMetadata `json:"data,omitempty"`
+}
+
+// PageMetadata is the structured data a page declares about itself in
+// meta tags and JSON-LD, often more precise than its text
+type PageMetadata struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	// OpenGraph holds the og:, article: and twitter: properties
+	OpenGraph map[string]string `json:"open_graph,omitempty"`
+	// Schema holds schema.org objects from JSON-LD, e.g. Article,
+	// Product, Recipe or FAQPage
+	Schema []map[string]any `json:"schema,omitempty"`
+}
+
+// openGraphPrefixes are the meta property namespaces kept in OpenGraph
+var openGraphPrefixes = []string{"og:", "article:", "twitter:", "book:", "profile:", "product:", "video:", "music:"}
+
+// schemaTypes are the schema.org types worth passing on, site chrome like
+// WebSite, BreadcrumbList or ImageObject is left out
+var schemaTypes = map[string]bool{
+	"Article": true, "NewsArticle": true, "BlogPosting": true, "TechArticle": true,
+	"ScholarlyArticle": true, "Report": true, "Product": true, "Offer": true,
+	"Recipe": true, "FAQPage": true, "QAPage": true, "HowTo": true, "Event": true,
+	"Review": true, "SoftwareApplication": true, "SoftwareSourceCode": true,
+	"Book": true, "Movie": true, "Course": true, "JobPosting": true,
+	"VideoObject": true, "Dataset": true, "Person": true, "Organization": true,
+	"LocalBusiness": true, "Place": true,
+}
+
+// schemaDropped are JSON-LD keys without value for an agent, articleBody
+// repeats the content
+var schemaDropped = []string{"@context", "articleBody", "potentialAction", "image", "logo"}
+
+// dateLayouts are the date formats found in pages, feeds and search APIs
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006",
+	"January 2, 2006",
+}
+
+// parseDate reads a date in one of the common formats, nil if none fits
+func parseDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// readPageMeta collects the author, site name, language, canonical url and
+// publish date of a page. It has to run before the head is cleaned away.
+func readPageMeta(doc *goquery.Document, pageURL string) pageMeta {
+	meta := func(selectors ...string) string {
+		for _, sel := range selectors {
+			if v := strings.TrimSpace(doc.Find(sel).First().AttrOr("content", "")); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	m := pageMeta{
+		Author:   meta(`meta[name="author"]`, `meta[property="article:author"]`, `meta[name="twitter:creator"]`),
+		SiteName: meta(`meta[property="og:site_name"]`, `meta[name="application-name"]`),
+		Language: strings.TrimSpace(doc.Find("html").AttrOr("lang", "")),
+		Published: parseDate(meta(`meta[property="article:published_time"]`, `meta[name="date"]`,
+			`meta[itemprop="datePublished"]`, `meta[name="dc.date"]`)),
+	}
+	if m.Published == nil {
+		m.Published = parseDate(doc.Find("time[datetime]").First().AttrOr("datetime", ""))
+	}
+	if m.Language == "" {
+		m.Language = meta(`meta[http-equiv="content-language"]`)
+	}
+	m.CanonicalURL = resolveRef(pageURL, doc.Find(`link[rel="canonical"]`).First().AttrOr("href", ""))
+	m.Data = readPageMetadata(doc, pageURL)
+	if m.Data != nil {
+		// JSON-LD fills in what meta tags left out
+		for _, obj := range m.Data.Schema {
+			if m.Author == "" {
+				m.Author = schemaName(obj["author"])
+			}
+			if m.SiteName == "" {
+				m.SiteName = schemaName(obj["publisher"])
+			}
+			if m.Published == nil {
+				date, _ := obj["datePublished"].(string)
+				m.Published = parseDate(date)
+			}
+		}
+	}
+	return m
+}
+
+// readPageMetadata collects the OpenGraph and meta tags and the JSON-LD
+// of a page, nil when there are none
+func readPageMetadata(doc *goquery.Document, pageURL string) *PageMetadata {
+	data := &PageMetadata{OpenGraph: make(map[string]string)}
+	named := make(map[string]string)
+	doc.Find("meta[content]").Each(func(i int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		// Twitter tags often use name instead of property
+		key := strings.ToLower(s.AttrOr("property", s.AttrOr("name", "")))
+		for _, prefix := range openGraphPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				if _, seen := data.OpenGraph[key]; !seen {
+					data.OpenGraph[key] = content
+				}
+				return
+			}
+		}
+		if _, seen := named[key]; key != "" && !seen {
+			named[key] = content
+		}
+	})
+	first := func(values ...string) string {
+		for _, v := range values {
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	og := data.OpenGraph
+	data.Title = first(og["og:title"], og["twitter:title"], normalizeSpace(doc.Find("title").First().Text()))
+	data.Description = first(og["og:description"], named["description"], og["twitter:description"])
+	data.Image = resolveRef(pageURL, first(og["og:image"], og["og:image:url"], og["twitter:image"]))
+	data.Type = og["og:type"]
+	for _, keyword := range strings.Split(named["keywords"], ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			data.Keywords = append(data.Keywords, keyword)
+		}
+	}
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var v any
+		if err := json.Unmarshal([]byte(s.Text()), &v); err != nil {
+			return
+		}
+		data.Schema = append(data.Schema, schemaObjects(v)...)
+	})
+	if len(data.OpenGraph) == 0 {
+		data.OpenGraph = nil
+	}
+	if data.Title == "" && data.Description == "" && data.OpenGraph == nil && data.Keywords == nil && data.Schema == nil {
+		return nil
+	}
+	return data
+}
+
+// schemaObjects returns the objects of interesting types in a JSON-LD
+// document, which may be one object, a list or a '@graph'
+func schemaObjects(v any) []map[string]any {
+	switch v := v.(type) {
+	case []any:
+		var objects []map[string]any
+		for _, item := range v {
+			objects = append(objects, schemaObjects(item)...)
+		}
+		return objects
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			return schemaObjects(graph)
+		}
+		if !schemaTypeWanted(v["@type"]) {
+			return nil
+		}
+		for _, key := range schemaDropped {
+			delete(v, key)
+		}
+		return []map[string]any{v}
+	default:
+		return nil
+	}
+}
+
+// schemaTypeWanted reports whether a '@type', a name or a list of them,
+// is one of schemaTypes
+func schemaTypeWanted(t any) bool {
+	switch t := t.(type) {
+	case string:
+		return schemaTypes[t]
+	case []any:
+		for _, item := range t {
+			if schemaTypeWanted(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaName returns the name of a schema.org Person or Organization,
+// which may also be given as a plain string or a list
+func schemaName(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]any:
+		name, _ := v["name"].(string)
+		return name
+	case []any:
+		var names []string
+		for _, item := range v {
+			if name := schemaName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// resolveRef makes a reference found on the page absolute
+func resolveRef(pageURL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
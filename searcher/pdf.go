@@ -0,0 +1,398 @@
+package searcher
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Limits of PDF extraction, a small file of many compressed streams
+// could inflate to gigabytes
+const (
+	// pdfStreamLimit caps the inflated size of a single stream
+	pdfStreamLimit = 8 << 20
+	// pdfInflateLimit caps the inflated size of all streams of a document
+	pdfInflateLimit = 32 << 20
+	// pdfTextLimit stops extraction once there is enough text, cleaning
+	// spaces and blank lines away leaves room for contentLimit
+	pdfTextLimit = 2 * contentLimit
+)
+
+// extractPDFText pulls the text out of the content streams of a PDF.
+// It only knows uncompressed and FlateDecode streams, single byte font
+// encodings and ToUnicode CMaps, which covers most generated documents.
+// Scanned documents and fonts without a usable encoding give an empty string.
+func extractPDFText(data []byte) string {
+	var sb strings.Builder
+	inflated := int64(0)
+	fonts := readPDFFonts(data, &inflated)
+	for rest := data; sb.Len() < pdfTextLimit; {
+		stream, dict, next := nextPDFStream(rest)
+		if next == nil {
+			break
+		}
+		rest = next
+		if bytes.Contains(dict, []byte("/Image")) || bytes.Contains(dict, []byte("/ObjStm")) || bytes.Contains(dict, []byte("/XRef")) {
+			continue
+		}
+		stream, ok := inflatePDFStream(stream, dict, &inflated)
+		if !ok {
+			continue
+		}
+		if text := pdfContentText(stream, fonts, pdfTextLimit-sb.Len()); text != "" {
+			sb.WriteString(text)
+			sb.WriteString("\n\n")
+		}
+	}
+	text := cleanPDFText(sb.String())
+	if !mostlyText(text) {
+		return ""
+	}
+	return text
+}
+
+// nextPDFStream finds the next stream of the file and returns its raw data,
+// its dictionary and the data after it, nil when there are no more streams
+func nextPDFStream(data []byte) (stream, dict, rest []byte) {
+	for {
+		start := bytes.Index(data, []byte("stream"))
+		if start < 0 {
+			return nil, nil, nil
+		}
+		after := data[start+len("stream"):]
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			data = after
+			continue
+		}
+		// The keyword is followed by an end of line before the data
+		switch {
+		case bytes.HasPrefix(after, []byte("\r\n")):
+			after = after[2:]
+		case bytes.HasPrefix(after, []byte("\n")), bytes.HasPrefix(after, []byte("\r")):
+			after = after[1:]
+		default:
+			data = after
+			continue
+		}
+		end := bytes.Index(after, []byte("endstream"))
+		if end < 0 {
+			return nil, nil, nil
+		}
+		head := data[:start]
+		if obj := bytes.LastIndex(head, []byte("obj")); obj >= 0 {
+			dict = head[obj:]
+		}
+		return bytes.TrimRight(after[:end], "\r\n"), dict, after[end+len("endstream"):]
+	}
+}
+
+// pdfContentText runs the text operators of a content stream and returns
+// the strings they show in the fonts they select, with line breaks where
+// the text moves down. It stops once the text is longer than limit.
+func pdfContentText(stream []byte, fonts map[string]*pdfFont, limit int) string {
+	var sb strings.Builder
+	var operands []pdfToken
+	var font *pdfFont
+	inText := false
+	lex := pdfLexer{data: stream}
+	for sb.Len() < limit {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		if tok.kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.text {
+		case "Tf":
+			if len(operands) > 0 && operands[0].kind == pdfName {
+				font = fonts[operands[0].text]
+			}
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			sb.WriteString("\n")
+		case "Tj", "TJ":
+			if inText {
+				writePDFStrings(&sb, operands, font)
+			}
+		case "'", "\"":
+			if inText {
+				sb.WriteString("\n")
+				writePDFStrings(&sb, operands, font)
+			}
+		case "T*":
+			sb.WriteString("\n")
+		case "Td", "TD":
+			// Moving down starts a new line, moving right a new word
+			if len(operands) >= 2 && operands[len(operands)-1].number() != 0 {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString(" ")
+			}
+		case "Tm":
+			sb.WriteString("\n")
+		}
+		operands = operands[:0]
+	}
+	return sb.String()
+}
+
+// writePDFStrings writes the strings of Tj and TJ operands, large
+// negative kerning in TJ arrays stands for a space between words.
+// Strings without a usable encoding are left out.
+func writePDFStrings(sb *strings.Builder, operands []pdfToken, font *pdfFont) {
+	for _, op := range operands {
+		switch op.kind {
+		case pdfString:
+			if text, ok := decodePDFString([]byte(op.text), font); ok {
+				sb.WriteString(text)
+			}
+		case pdfNumber:
+			if op.number() < -200 {
+				sb.WriteString(" ")
+			}
+		}
+	}
+}
+
+// pdfTokenKind is the kind of a content stream token
+type pdfTokenKind int
+
+const (
+	pdfOperator pdfTokenKind = iota
+	pdfNumber
+	pdfString
+	pdfName
+	pdfOther
+)
+
+type pdfToken struct {
+	kind pdfTokenKind
+	text string
+}
+
+func (t pdfToken) number() float64 {
+	if t.kind != pdfNumber {
+		return 0
+	}
+	n, _ := strconv.ParseFloat(t.text, 64)
+	return n
+}
+
+// pdfLexer splits a content stream into tokens
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			// A comment runs to the end of the line
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: pdfString, text: string(l.literal())}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<',
+			c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return pdfToken{kind: pdfOther}, true
+		case c == '<':
+			return pdfToken{kind: pdfString, text: string(l.hex())}, true
+		case c == '[' || c == ']' || c == '{' || c == '}' || c == '>' || c == ')':
+			// Arrays keep their elements as operands of the next operator
+			l.pos++
+		case c == '/':
+			l.pos++
+			return pdfToken{kind: pdfName, text: l.word()}, true
+		default:
+			word := l.word()
+			if word == "" {
+				l.pos++
+				continue
+			}
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				return pdfToken{kind: pdfNumber, text: word}, true
+			}
+			return pdfToken{kind: pdfOperator, text: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// word reads regular characters up to the next delimiter
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || strings.IndexByte("()<>[]{}/%", c) >= 0 {
+			break
+		}
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// literal reads a string in parentheses, which may nest and hold escapes
+func (l *pdfLexer) literal() []byte {
+	var out []byte
+	depth := 0
+	l.pos++
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return out
+			}
+			depth--
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// A line continuation
+				if e == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				n := int(e - '0')
+				for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+					n = n*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				c = byte(n)
+			default:
+				c = e
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hex reads a string of hex digits in angle brackets
+func (l *pdfLexer) hex() []byte {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		n, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(n)
+	}
+	return out
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// decodePDFString decodes a shown string with the ToUnicode CMap of its
+// font, strings with a byte order mark as UTF-16 and strings of simple
+// fonts as WinAnsi. Composite fonts without a CMap show glyph ids, as do
+// two byte strings with a zero high byte in unknown fonts, ok is false for them.
+func decodePDFString(b []byte, font *pdfFont) (string, bool) {
+	switch {
+	case font != nil && font.cmap != nil:
+		return font.cmap.decode(b)
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units)), true
+	case font != nil && font.composite:
+		return "", false
+	case font == nil && len(b) >= 2 && len(b)%2 == 0 && b[0] == 0:
+		return "", false
+	}
+	s, err := charmap.Windows1252.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b), true
+	}
+	return string(s), true
+}
+
+// cleanPDFText drops control characters, collapses spaces in lines
+// and runs of blank lines
+func cleanPDFText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, text)
+	var lines []string
+	blank := true
+	for _, line := range strings.Split(text, "\n") {
+		line = normalizeSpace(line)
+		if line == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// mostlyText reports whether letters, digits, spaces and punctuation make
+// up most of the text, glyph ids of unknown fonts come out as noise
+func mostlyText(text string) bool {
+	if text == "" {
+		return false
+	}
+	good, total := 0, 0
+	for _, r := range text {
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) {
+			good++
+		}
+	}
+	return float64(good)/float64(total) >= 0.8
+}
@@ -0,0 +1,158 @@
+package searcher
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+)
+
+func TestPDFLexer(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []pdfToken
+	}{
+		{"literal", "(Hello) Tj", []pdfToken{{pdfString, "Hello"}, {pdfOperator, "Tj"}}},
+		{"nested parens", "(a (b) c) Tj", []pdfToken{{pdfString, "a (b) c"}, {pdfOperator, "Tj"}}},
+		{"escapes", `(a\)b\n\101) Tj`, []pdfToken{{pdfString, "a)b\nA"}, {pdfOperator, "Tj"}}},
+		{"hex", "<48656C6C6F> Tj", []pdfToken{{pdfString, "Hello"}, {pdfOperator, "Tj"}}},
+		{"odd hex", "<414> Tj", []pdfToken{{pdfString, "A@"}, {pdfOperator, "Tj"}}},
+		{"raw hex", "<FEFF0048> Tj", []pdfToken{{pdfString, "\xfe\xff\x00H"}, {pdfOperator, "Tj"}}},
+		{"numbers and names", "/F1 12 Tf 1.5 -2 Td", []pdfToken{
+			{pdfName, "F1"}, {pdfNumber, "12"}, {pdfOperator, "Tf"},
+			{pdfNumber, "1.5"}, {pdfNumber, "-2"}, {pdfOperator, "Td"},
+		}},
+		{"array", "[(A) -300 (B)] TJ", []pdfToken{
+			{pdfString, "A"}, {pdfNumber, "-300"}, {pdfString, "B"}, {pdfOperator, "TJ"},
+		}},
+		{"comment", "% note\n(x) Tj", []pdfToken{{pdfString, "x"}, {pdfOperator, "Tj"}}},
+		{"dictionary", "<< /Type /Page >> BT", []pdfToken{
+			{pdfOther, ""}, {pdfName, "Type"}, {pdfName, "Page"}, {pdfOther, ""}, {pdfOperator, "BT"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lex := pdfLexer{data: []byte(tt.data)}
+			var got []pdfToken
+			for {
+				tok, ok := lex.next()
+				if !ok {
+					break
+				}
+				got = append(got, tok)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("tokens = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("token %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodePDFString(t *testing.T) {
+	cmap := parseCMap([]byte(testCMap))
+	tests := []struct {
+		name   string
+		data   string
+		font   *pdfFont
+		want   string
+		wantOK bool
+	}{
+		{"winansi", "caf\xe9 \x93q\x94", nil, "café “q”", true},
+		{"utf16 bom", "\xfe\xff\x00H\x00i", nil, "Hi", true},
+		{"glyph ids in unknown font", "\x00\x2b\x00\x48", nil, "", false},
+		{"composite without cmap", "\x00\x2b\x00\x48", &pdfFont{composite: true}, "", false},
+		{"simple font", "\x00\x2b", &pdfFont{}, "\x00+", true},
+		{"cmap", "\x00\x2b\x00\x48\x00\x4f\x00\x4f\x00\x52", &pdfFont{composite: true, cmap: cmap}, "Hello", true},
+		{"cmap ligature", "\x00\x60", &pdfFont{composite: true, cmap: cmap}, "fi", true},
+		{"cmap array range", "\x00\x71\x00\x70", &pdfFont{composite: true, cmap: cmap}, "yx", true},
+		{"unmapped codes", "\x01\x00", &pdfFont{composite: true, cmap: cmap}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := decodePDFString([]byte(tt.data), tt.font)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("decodePDFString = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// testCMap maps the glyph ids of "Hello" and a few special cases
+const testCMap = `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<002B> <0048>
+<0060> <00660069>
+endbfchar
+2 beginbfrange
+<0048> <0052> <0065>
+<0070> <0071> [<0078> <0079>]
+endbfrange
+endcmap`
+
+func TestPDFContentText(t *testing.T) {
+	tests := []struct {
+		stream string
+		want   string
+	}{
+		{"BT (Hello) Tj ET", "Hello\n"},
+		{"BT [(Hel) -20 (lo) -500 (World)] TJ ET", "Hello World\n"},
+		{"BT (One) Tj 0 -14 Td (Two) Tj ET", "One\nTwo\n"},
+		{"BT (One) Tj 50 0 Td (Two) Tj ET", "One Two\n"},
+		{"(outside) Tj", ""},
+		{"BT <002B00480048004F0052> Tj ET", "\n"},
+		{"BT /F1 12 Tf <002B0048004F004F0052> Tj ET", "Hello\n"},
+		{"BT /F2 12 Tf <002B0048> Tj ET", "\n"},
+	}
+	fonts := map[string]*pdfFont{
+		"F1": {composite: true, cmap: parseCMap([]byte(testCMap))},
+		"F2": {composite: true},
+	}
+	for _, tt := range tests {
+		if got := pdfContentText([]byte(tt.stream), fonts, pdfTextLimit); got != tt.want {
+			t.Errorf("pdfContentText(%q) = %q, want %q", tt.stream, got, tt.want)
+		}
+	}
+}
+
+func TestExtractPDFText(t *testing.T) {
+	var flate bytes.Buffer
+	w := zlib.NewWriter(&flate)
+	w.Write([]byte("BT (Compressed text) Tj ET"))
+	w.Close()
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Length 30 >>\nstream\nBT (Plain text) Tj ET\nendstream\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Filter /FlateDecode >>\nstream\n")
+	pdf.Write(flate.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Subtype /Image /Filter /DCTDecode >>\nstream\n\xff\xd8\xff\nendstream\nendobj\n")
+	want := "Plain text\n\nCompressed text"
+	if got := extractPDFText(pdf.Bytes()); got != want {
+		t.Errorf("extractPDFText = %q, want %q", got, want)
+	}
+	if got := extractPDFText([]byte("%PDF-1.4\nno streams")); got != "" {
+		t.Errorf("extractPDFText without streams = %q, want empty", got)
+	}
+}
+
+func TestExtractPDFTextToUnicode(t *testing.T) {
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Page /Resources << /Font << /F1 2 0 R >> >> /Contents 4 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Font /Subtype /Type0 /Encoding /Identity-H /ToUnicode 3 0 R >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Length 300 >>\nstream\n" + testCMap + "\nendstream\nendobj\n")
+	pdf.WriteString("4 0 obj\n<< /Length 50 >>\nstream\nBT /F1 12 Tf <002B0048004F004F0052> Tj ET\nendstream\nendobj\n")
+	if got := extractPDFText(pdf.Bytes()); got != "Hello" {
+		t.Errorf("extractPDFText = %q, want %q", got, "Hello")
+	}
+	if got := extractPDFText([]byte("%PDF-1.4\n1 0 obj\n<< >>\nstream\nBT <002B00480048004F0052> Tj ET\nendstream\nendobj\n")); got != "" {
+		t.Errorf("extractPDFText without streams = %q, want empty", got)
+	}
+}
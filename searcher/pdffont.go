@@ -0,0 +1,248 @@
+package searcher
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+)
+
+// PDF objects and font references, found with patterns instead of a
+// full parser. Objects packed into compressed object streams are missed,
+// their fonts then count as unknown.
+var (
+	pdfObjectStart = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfFontDict    = regexp.MustCompile(`/Font\s*<<((?s:[^>]*))>>`)
+	pdfFontRefDict = regexp.MustCompile(`/Font\s+(\d+)\s+\d+\s+R`)
+	pdfNamedRef    = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+	pdfToUnicode   = regexp.MustCompile(`/ToUnicode\s+(\d+)\s+\d+\s+R`)
+	pdfComposite   = regexp.MustCompile(`/Type0\b|/Identity-[HV]\b`)
+)
+
+// Sections of a ToUnicode CMap
+var (
+	cmapCodespace = regexp.MustCompile(`begincodespacerange\s*<([0-9A-Fa-f]+)>`)
+	cmapChars     = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	cmapRanges    = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	cmapCharLine  = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]*)>`)
+	cmapRangeLine = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f]*>|\[[^\]]*\])`)
+	cmapHex       = regexp.MustCompile(`<([0-9A-Fa-f]*)>`)
+)
+
+// pdfFont is what extraction needs to know about a font
+type pdfFont struct {
+	// composite fonts show glyph ids, only a cmap makes text of them
+	composite bool
+	cmap      *pdfCMap
+}
+
+// pdfCMap maps character codes to text
+type pdfCMap struct {
+	// width is the byte length of a code
+	width  int
+	chars  map[uint32]string
+	ranges []cmapRange
+}
+
+// cmapRange maps lo..hi to consecutive characters from dst, or to
+// the strings of list
+type cmapRange struct {
+	lo, hi uint32
+	dst    []uint16
+	list   []string
+}
+
+// pdfObjects indexes the bodies of a document's objects by number
+func pdfObjects(data []byte) map[string][]byte {
+	objects := make(map[string][]byte)
+	matches := pdfObjectStart.FindAllSubmatchIndex(data, -1)
+	for i, m := range matches {
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		num := string(data[m[2]:m[3]])
+		if _, seen := objects[num]; !seen {
+			objects[num] = data[m[1]:end]
+		}
+	}
+	return objects
+}
+
+// readPDFFonts returns the fonts of a document by resource name. Pages
+// reusing a name for another font get the first one.
+func readPDFFonts(data []byte, inflated *int64) map[string]*pdfFont {
+	objects := pdfObjects(data)
+	refs := make(map[string]string)
+	addRefs := func(dict []byte) {
+		for _, m := range pdfNamedRef.FindAllSubmatch(dict, -1) {
+			if _, seen := refs[string(m[1])]; !seen {
+				refs[string(m[1])] = string(m[2])
+			}
+		}
+	}
+	for _, body := range objects {
+		for _, m := range pdfFontDict.FindAllSubmatch(body, -1) {
+			addRefs(m[1])
+		}
+		for _, m := range pdfFontRefDict.FindAllSubmatch(body, -1) {
+			addRefs(objects[string(m[1])])
+		}
+	}
+	fonts := make(map[string]*pdfFont, len(refs))
+	cmaps := make(map[string]*pdfCMap)
+	for name, num := range refs {
+		body := objects[num]
+		font := &pdfFont{composite: pdfComposite.Match(body)}
+		if m := pdfToUnicode.FindSubmatch(body); m != nil {
+			ref := string(m[1])
+			if _, seen := cmaps[ref]; !seen {
+				cmaps[ref] = nil
+				if stream, dict, next := nextPDFStream(objects[ref]); next != nil {
+					if stream, ok := inflatePDFStream(stream, dict, inflated); ok {
+						cmaps[ref] = parseCMap(stream)
+					}
+				}
+			}
+			font.cmap = cmaps[ref]
+		}
+		fonts[name] = font
+	}
+	return fonts
+}
+
+// inflatePDFStream returns the data of an uncompressed or FlateDecode
+// stream, counting inflated bytes against pdfInflateLimit. ok is false
+// for other filters and once the limit is used up.
+func inflatePDFStream(stream, dict []byte, inflated *int64) ([]byte, bool) {
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return stream, true
+	}
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || *inflated >= pdfInflateLimit {
+		// DCT, LZW and friends hold images or are too rare to care
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	// A truncated stream still gives the text before the damage
+	data, _ := io.ReadAll(io.LimitReader(r, min(pdfStreamLimit, pdfInflateLimit-*inflated)))
+	*inflated += int64(len(data))
+	return data, true
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap,
+// nil when it has none
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{chars: make(map[uint32]string)}
+	if m := cmapCodespace.FindSubmatch(data); m != nil {
+		cmap.width = len(m[1]) / 2
+	}
+	for _, section := range cmapChars.FindAllSubmatch(data, -1) {
+		for _, m := range cmapCharLine.FindAllSubmatch(section[1], -1) {
+			cmap.setWidth(m[1])
+			cmap.chars[hexCode(m[1])] = utf16Text(m[2])
+		}
+	}
+	for _, section := range cmapRanges.FindAllSubmatch(data, -1) {
+		for _, m := range cmapRangeLine.FindAllSubmatch(section[1], -1) {
+			cmap.setWidth(m[1])
+			r := cmapRange{lo: hexCode(m[1]), hi: hexCode(m[2])}
+			if bytes.HasPrefix(m[3], []byte("[")) {
+				for _, item := range cmapHex.FindAllSubmatch(m[3], -1) {
+					r.list = append(r.list, utf16Text(item[1]))
+				}
+			} else {
+				r.dst = utf16Units(bytes.Trim(m[3], "<>"))
+				if len(r.dst) == 0 {
+					continue
+				}
+			}
+			cmap.ranges = append(cmap.ranges, r)
+		}
+	}
+	if len(cmap.chars) == 0 && len(cmap.ranges) == 0 {
+		return nil
+	}
+	return cmap
+}
+
+// setWidth takes the code width from the first mapping when the
+// codespace didn't tell
+func (c *pdfCMap) setWidth(code []byte) {
+	if c.width == 0 {
+		c.width = max(len(code)/2, 1)
+	}
+}
+
+// decode maps the codes of a shown string to text, ok is false when
+// none of them has a mapping
+func (c *pdfCMap) decode(b []byte) (string, bool) {
+	var out []rune
+	mapped := false
+	for i := 0; i+c.width <= len(b); i += c.width {
+		code := uint32(0)
+		for _, x := range b[i : i+c.width] {
+			code = code<<8 | uint32(x)
+		}
+		if text, ok := c.lookup(code); ok {
+			out = append(out, []rune(text)...)
+			mapped = true
+		}
+	}
+	return string(out), mapped
+}
+
+func (c *pdfCMap) lookup(code uint32) (string, bool) {
+	if text, ok := c.chars[code]; ok {
+		return text, true
+	}
+	for _, r := range c.ranges {
+		if code < r.lo || code > r.hi {
+			continue
+		}
+		offset := code - r.lo
+		if r.list != nil {
+			if int(offset) < len(r.list) {
+				return r.list[offset], true
+			}
+			return "", false
+		}
+		units := append([]uint16(nil), r.dst...)
+		units[len(units)-1] += uint16(offset)
+		return string(utf16.Decode(units)), true
+	}
+	return "", false
+}
+
+// hexCode reads a character code in hex
+func hexCode(h []byte) uint32 {
+	n, _ := strconv.ParseUint(string(h), 16, 32)
+	return uint32(n)
+}
+
+// utf16Units decodes hex into UTF-16 code units
+func utf16Units(h []byte) []uint16 {
+	if len(h)%2 == 1 {
+		h = append(h[:len(h):len(h)], '0')
+	}
+	b := make([]byte, len(h)/2)
+	if _, err := hex.Decode(b, h); err != nil {
+		return nil
+	}
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return units
+}
+
+// utf16Text decodes hex holding UTF-16 text
+func utf16Text(h []byte) string {
+	return string(utf16.Decode(utf16Units(h)))
+}
@@ -0,0 +1,183 @@
+package searcher
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Politeness defaults, per host
+const (
+	defaultHostRate  = 2.0
+	defaultHostBurst = 4
+	// bucketIdleTTL is how long an unused bucket is kept, result pages
+	// point to countless hosts that are fetched once
+	bucketIdleTTL = 10 * time.Minute
+)
+
+// tokenBucket allows rate requests per second with bursts of up to burst
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long to wait until it is available
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// idle reports whether the bucket went unused for longer than ttl and
+// refilled since, dropping it then loses nothing
+func (b *tokenBucket) idle(now time.Time, ttl time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elapsed := now.Sub(b.last)
+	return elapsed > ttl && b.tokens+elapsed.Seconds()*b.rate >= b.burst
+}
+
+// slowDown lowers the rate, it never raises it
+func (b *tokenBucket) slowDown(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rate < b.rate {
+		b.rate = rate
+		b.burst = 1
+		b.tokens = min(b.tokens, b.burst)
+	}
+}
+
+// HostLimiter throttles requests with a token bucket per host.
+// It is shared by all searchers, so concurrent searches together
+// stay within the limits of every site.
+type HostLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	overrides map[string]float64
+	buckets   map[string]*tokenBucket
+	// lastSweep is when idle buckets were last dropped
+	lastSweep time.Time
+}
+
+// NewHostLimiter allows rate requests per second to every host, with bursts
+// of up to burst requests. overrides sets the rate of domains, they also
+// apply to subdomains.
+func NewHostLimiter(rate float64, burst int, overrides map[string]float64) *HostLimiter {
+	if rate <= 0 {
+		rate = defaultHostRate
+	}
+	if burst <= 0 {
+		burst = defaultHostBurst
+	}
+	normalized := make(map[string]float64, len(overrides))
+	for domain, r := range overrides {
+		if r > 0 {
+			normalized[strings.ToLower(strings.TrimPrefix(domain, "www."))] = r
+		}
+	}
+	return &HostLimiter{
+		rate:      rate,
+		burst:     burst,
+		overrides: normalized,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// bucket returns the bucket of the host, creating it on first use
+func (l *HostLimiter) bucket(host string) *tokenBucket {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[host]; ok {
+		return b
+	}
+	if now := time.Now(); now.Sub(l.lastSweep) > bucketIdleTTL {
+		for h, b := range l.buckets {
+			if b.idle(now, bucketIdleTTL) {
+				delete(l.buckets, h)
+			}
+		}
+		l.lastSweep = now
+	}
+	b := newTokenBucket(l.rateOf(host), l.burst)
+	l.buckets[host] = b
+	return b
+}
+
+// rateOf returns the override of the closest matching domain
+// or the default rate
+func (l *HostLimiter) rateOf(host string) float64 {
+	for domain := host; domain != ""; {
+		if r, ok := l.overrides[domain]; ok {
+			return r
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return l.rate
+}
+
+// Wait blocks until a request to the host is allowed or ctx is done
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	wait := l.bucket(host).reserve()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SetMinInterval slows the host down to at most one request per interval,
+// e.g. for a crawl delay the site asked for
+func (l *HostLimiter) SetMinInterval(host string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	l.bucket(host).slowDown(float64(time.Second) / float64(interval))
+}
+
+// rateLimitTransport waits for the host limiter before every request
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter *HostLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,178 @@
+package searcher
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// ExtractMode selects which part of a page becomes the result content
+type ExtractMode string
+
+const (
+	// ExtractMain keeps the main content block, like reader modes do,
+	// and falls back to the whole page when no block stands out
+	ExtractMain ExtractMode = "main"
+	// ExtractPage keeps the text of the whole page
+	ExtractPage ExtractMode = "page"
+)
+
+// minMainContent is the text length a block needs to count as main content
+const minMainContent = 250
+
+var (
+	// boilerplateAttr matches class and id values of page chrome
+	boilerplateAttr = regexp.MustCompile(`(?i)cookie|consent|banner|navbar|\bnav\b|menu|breadcrumb|footer|sidebar|comment|share|social|related|promo|advert|\bads?\b|popup|modal|newsletter|subscribe`)
+	// positiveAttr and negativeAttr weight candidate blocks by class and id
+	positiveAttr = regexp.MustCompile(`(?i)article|content|entry|main|post|story|text|body`)
+	negativeAttr = regexp.MustCompile(`(?i)comment|footer|sidebar|widget|meta|nav|menu|related|share|social|promo|ad-`)
+)
+
+// removeBoilerplate drops navigation, banners, footers and similar chrome,
+// leaving alone elements that wrap the article
+func removeBoilerplate(doc *goquery.Document) {
+	doc.Find("nav, header, footer, aside, form, iframe, button, dialog, [role=navigation], [role=banner], [role=contentinfo], [aria-hidden=true]").Each(func(i int, s *goquery.Selection) {
+		if s.Find("article, main").Length() > 0 {
+			return
+		}
+		if s.Is("header, footer") && s.Closest("article, main").Length() > 0 {
+			// The article's own title, byline or notes, not the page's
+			return
+		}
+		s.Remove()
+	})
+	doc.Find("div, section, ul, span, p").Each(func(i int, s *goquery.Selection) {
+		attrs := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if strings.TrimSpace(attrs) == "" || !boilerplateAttr.MatchString(attrs) {
+			return
+		}
+		if s.Closest("pre, code").Length() > 0 {
+			// Highlighters mark up code with classes like 'hljs-comment'
+			return
+		}
+		if s.Find("article, main").Length() > 0 || proseParagraphs(s) >= 3 {
+			// A wrapper around the article, e.g. 'content has-sidebar'
+			return
+		}
+		s.Remove()
+	})
+}
+
+// proseParagraphs counts paragraphs long enough to be article text
+func proseParagraphs(s *goquery.Selection) int {
+	count := 0
+	s.Find("p").Each(func(i int, p *goquery.Selection) {
+		if len(normalizeSpace(p.Text())) >= 80 {
+			count++
+		}
+	})
+	return count
+}
+
+// mainContent returns the block holding the page's main text,
+// nil if none stands out
+func mainContent(doc *goquery.Document) *goquery.Selection {
+	// Semantic markup is the strongest hint
+	var best *goquery.Selection
+	bestLen := 0
+	doc.Find("article, main, [role=main], [itemprop=articleBody]").Each(func(i int, s *goquery.Selection) {
+		if n := len(normalizeSpace(s.Text())); n > bestLen {
+			best, bestLen = s, n
+		}
+	})
+	if bestLen >= minMainContent {
+		return best
+	}
+	return scoredContent(doc)
+}
+
+// scoredContent scores blocks by the paragraphs they hold, their text
+// density and their link density, and returns the best one
+func scoredContent(doc *goquery.Document) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+	add := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			order = append(order, n)
+			scores[n] = classWeight(n)
+		}
+		scores[n] += score
+	}
+	doc.Find("p, pre, td, blockquote, li").Each(func(i int, s *goquery.Selection) {
+		text := normalizeSpace(s.Text())
+		if len(text) < 25 {
+			return
+		}
+		// Longer paragraphs with more commas look more like prose
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		parent := s.Nodes[0].Parent
+		add(parent, score)
+		if parent != nil {
+			add(parent.Parent, score/2)
+		}
+	})
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(goquery.NewDocumentFromNode(n).Selection))
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	sel := goquery.NewDocumentFromNode(best).Selection
+	if len(normalizeSpace(sel.Text())) < minMainContent {
+		return nil
+	}
+	return sel
+}
+
+// classWeight rewards and punishes blocks by their class and id
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, attr := range n.Attr {
+		if attr.Key != "class" && attr.Key != "id" {
+			continue
+		}
+		if positiveAttr.MatchString(attr.Val) {
+			weight += 25
+		}
+		if negativeAttr.MatchString(attr.Val) {
+			weight -= 25
+		}
+	}
+	switch n.Data {
+	case "article", "main":
+		weight += 10
+	case "div", "section":
+		weight += 5
+	case "ul", "ol", "td", "th":
+		weight -= 3
+	}
+	return weight
+}
+
+// linkDensity is the share of the block's text that sits in links
+func linkDensity(s *goquery.Selection) float64 {
+	total := len(normalizeSpace(s.Text()))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	s.Find("a").Each(func(i int, a *goquery.Selection) {
+		links += len(normalizeSpace(a.Text()))
+	})
+	return min(float64(links)/float64(total), 1)
+}
+
+// normalizeSpace collapses runs of whitespace into single spaces
+func normalizeSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
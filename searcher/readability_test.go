@@ -0,0 +1,63 @@
+package searcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestRemoveBoilerplate(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		keep []string
+		drop []string
+	}{
+		{
+			"page chrome",
+			`<header>Site</header><nav>Menu</nav><main><p>Body</p></main><footer>Copyright</footer>`,
+			[]string{"Body"}, []string{"Site", "Menu", "Copyright"},
+		},
+		{
+			"article header and footer",
+			`<article><header><h1>Title</h1><p>By Ann</p></header><p>Body</p><footer>Notes</footer></article>`,
+			[]string{"Title", "By Ann", "Body", "Notes"}, nil,
+		},
+		{
+			"nav inside article",
+			`<article><nav>Contents</nav><p>Body</p></article>`,
+			[]string{"Body"}, []string{"Contents"},
+		},
+		{
+			"wrapper around main",
+			`<div class="content has-sidebar"><main><p>Body</p></main></div><div class="sidebar">Links</div>`,
+			[]string{"Body"}, []string{"Links"},
+		},
+		{
+			"highlighted code",
+			`<pre><code><span class="hljs-comment">// note</span></code></pre>`,
+			[]string{"// note"}, nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			removeBoilerplate(doc)
+			text := doc.Text()
+			for _, s := range tt.keep {
+				if !strings.Contains(text, s) {
+					t.Errorf("text %q lost %q", text, s)
+				}
+			}
+			for _, s := range tt.drop {
+				if strings.Contains(text, s) {
+					t.Errorf("text %q kept %q", text, s)
+				}
+			}
+		})
+	}
+}
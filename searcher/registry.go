@@ -0,0 +1,258 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GrailFinder/searchagent/config"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Names of the search types the registry knows about
+const (
+	TypeScraper  = "scraper"
+	TypeAPI      = "api"
+	TypeAll      = "all"
+	TypeFallback = "fallback"
+	TypeNews     = "news"
+	// TypeGeneral is an alias of the default search type
+	TypeGeneral = "general"
+)
+
+// reservedTypes are the built-in search types, searx instances can't use their names
+var reservedTypes = []string{TypeScraper, TypeAPI, TypeAll, TypeFallback, TypeNews, TypeGeneral}
+
+// ErrUnknownSearchType is returned for search types missing from the registry
+var ErrUnknownSearchType = errors.New("unknown search type")
+
+// ErrInvalidLimit is returned for searches asking for no results at all
+var ErrInvalidLimit = errors.New("limit must be positive")
+
+// sharedTransport is reused by every client so searchers keep their
+// connections alive between requests
+var sharedTransport = &http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+	ForceAttemptHTTP2:   true,
+}
+
+// NewHTTPClient creates a client on top of the shared transport,
+// repeating failed requests according to the retry policy.
+// Every try waits for the limiter of its host, a nil limiter disables it.
+func NewHTTPClient(timeout time.Duration, retry RetryPolicy, limiter *HostLimiter) *http.Client {
+	var transport http.RoundTripper = sharedTransport
+	if limiter != nil {
+		transport = &rateLimitTransport{next: transport, limiter: limiter}
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &retryTransport{
+			next:   transport,
+			policy: retry,
+		},
+	}
+}
+
+// Registry holds long-lived searchers keyed by search type
+type Registry struct {
+	searchers   map[string]Searcher
+	defaultType string
+}
+
+// NewRegistry builds every searcher described by the config once,
+// so they can be shared between requests
+func NewRegistry(cfg *config.Config) (*Registry, error) {
+	timeout := defaultTimeout
+	if cfg.HTTPTimeout > 0 {
+		timeout = time.Duration(cfg.HTTPTimeout) * time.Second
+	}
+	// A negative rate disables throttling
+	var limiter *HostLimiter
+	if cfg.RateLimit >= 0 {
+		limiter = NewHostLimiter(cfg.RateLimit, cfg.RateBurst, cfg.RateLimitOverrides)
+	}
+	policy := retryPolicy(cfg)
+	client := NewHTTPClient(timeout, policy, limiter)
+	r := &Registry{
+		searchers:   make(map[string]Searcher),
+		defaultType: TypeFallback,
+	}
+	// A negative ttl disables caching
+	cacheTTL := defaultCacheTTL
+	if cfg.CacheTTL != 0 {
+		cacheTTL = time.Duration(cfg.CacheTTL) * time.Second
+	}
+	cached := func(s Searcher) Searcher {
+		if cacheTTL < 0 {
+			return s
+		}
+		return NewCachedSearcher(s, positiveOr(cfg.CacheSize, defaultCacheSize), cacheTTL)
+	}
+	maxBody := int64(positiveOr(cfg.MaxBodyBytes, defaultMaxBodyBytes))
+	scraper := NewWebScraper(cfg.ScraperURL, client)
+	// DuckDuckGo blocks are backed off by the scraper instead of retried
+	scraper.SetSearchClient(NewHTTPClient(timeout, policy.without(blockStatuses...), limiter))
+	scraper.SetFetchLimits(cfg.FetchConcurrency, time.Duration(cfg.FetchDeadline)*time.Second)
+	scraper.SetMaxBodyBytes(maxBody)
+	scraper.SetBlockCooldown(time.Duration(cfg.BlockCooldown) * time.Second)
+	switch mode := ExtractMode(cfg.ExtractMode); mode {
+	case "":
+	case ExtractMain, ExtractPage:
+		scraper.SetExtractMode(mode)
+	default:
+		return nil, fmt.Errorf("unknown extract mode: %s", mode)
+	}
+	if cfg.RobotsEnabled {
+		scraper.SetRobotsChecker(NewRobotsChecker(client, limiter))
+	}
+	if cacheTTL > 0 {
+		scraper.SetPageCache(positiveOr(cfg.PageCacheSize, defaultPageCacheSize), cacheTTL)
+	}
+	if cfg.PageCacheDir != "" {
+		err := scraper.SetDiskCache(cfg.PageCacheDir, int64(cfg.PageCacheMaxMB)<<20, max(cacheTTL, 0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open page cache: %w", err)
+		}
+	}
+	// Only backends are cached, combining searchers reuse their cached pages
+	r.Register(TypeScraper, cached(scraper))
+	searx := func(baseURL string) Searcher {
+		s := NewSearXNGAPISearcher(baseURL, client)
+		s.SetMaxBodyBytes(maxBody)
+		s.SetDefaultFilters(cfg.SearXCategories, cfg.SearXEngines)
+		return cached(s)
+	}
+	r.Register(TypeAPI, searx(cfg.SEARXAPI))
+	for name, instanceURL := range cfg.SearXInstances {
+		if slices.Contains(reservedTypes, name) {
+			return nil, fmt.Errorf("searx instance name %q is taken by a built-in search type", name)
+		}
+		r.Register(name, searx(instanceURL))
+	}
+	// Combining searchers are built last, they only use searchers registered above
+	aggregate, err := r.named(cfg.AggregateTypes, []string{TypeScraper, TypeAPI})
+	if err != nil {
+		return nil, err
+	}
+	fallback, err := r.named(cfg.FallbackChain, []string{TypeScraper, TypeAPI})
+	if err != nil {
+		return nil, err
+	}
+	r.Register(TypeAll, NewAggregateSearcher(aggregate))
+	r.Register(TypeFallback, NewFallbackSearcher(fallback))
+	// News merges the news categories of the aggregated backends
+	r.Register(TypeNews, NewNewsSearcher(r.searchers[TypeAll]))
+	return r, nil
+}
+
+// named looks up searchers by type, the defaults are used for an empty list
+func (r *Registry) named(names []string, defaults []string) ([]NamedSearcher, error) {
+	if len(names) == 0 {
+		names = defaults
+	}
+	searchers := make([]NamedSearcher, 0, len(names))
+	for _, name := range names {
+		s, err := r.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		searchers = append(searchers, NamedSearcher{Name: r.Resolve(name), Searcher: s})
+	}
+	return searchers, nil
+}
+
+// retryPolicy returns the retry policy of the config,
+// unset fields are taken from the default policy
+func retryPolicy(cfg *config.Config) RetryPolicy {
+	policy := DefaultRetryPolicy
+	policy.MaxAttempts = positiveOr(cfg.RetryMaxAttempts, policy.MaxAttempts)
+	if cfg.RetryBaseDelayMS > 0 {
+		policy.BaseDelay = time.Duration(cfg.RetryBaseDelayMS) * time.Millisecond
+	}
+	if cfg.RetryMaxDelayMS > 0 {
+		policy.MaxDelay = time.Duration(cfg.RetryMaxDelayMS) * time.Millisecond
+	}
+	if cfg.RetryJitter > 0 && cfg.RetryJitter <= 1 {
+		policy.Jitter = cfg.RetryJitter
+	}
+	if len(cfg.RetryStatuses) > 0 {
+		policy.RetryStatuses = cfg.RetryStatuses
+	}
+	return policy
+}
+
+// positiveOr returns v if it is positive and def otherwise
+func positiveOr(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// Register adds or replaces the searcher for the given type
+func (r *Registry) Register(name string, s Searcher) {
+	r.searchers[name] = s
+}
+
+// Resolve returns the search type a name refers to.
+// Empty type and "general" resolve to the default searcher.
+func (r *Registry) Resolve(name string) string {
+	if name == "" || name == TypeGeneral {
+		return r.defaultType
+	}
+	return name
+}
+
+// Get returns the searcher for the given type
+func (r *Registry) Get(name string) (Searcher, error) {
+	name = r.Resolve(name)
+	s, ok := r.searchers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, known types: %s", ErrUnknownSearchType, name, strings.Join(r.Names(), ", "))
+	}
+	return s, nil
+}
+
+// Search runs the search on the searcher of the given type,
+// the page's Backend names the searcher that served it
+func (r *Registry) Search(ctx context.Context, searchType string, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	// Searchers slice pages by limit, a following page of none never ends
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidLimit, limit)
+	}
+	s, err := r.Get(searchType)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.Search(ctx, query, limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	if page.Backend == "" {
+		page.Backend = r.Resolve(searchType)
+	}
+	if opts.Sort == SortDate {
+		sortByDate(page.Results)
+	}
+	return page, nil
+}
+
+// Names returns registered search types in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.searchers))
+	for name := range r.searchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
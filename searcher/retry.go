@@ -0,0 +1,121 @@
+package searcher
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RetryPolicy describes how failed requests are repeated
+type RetryPolicy struct {
+	// MaxAttempts counts the first try, 1 disables retries
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubled on every next one
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter is the fraction of the delay randomly taken off, 0..1
+	Jitter float64
+	// RetryStatuses are response codes worth another try
+	RetryStatuses []int
+}
+
+// DefaultRetryPolicy is used when the config doesn't set one
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.5,
+	RetryStatuses: []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// without returns the policy with the given statuses no longer retried
+func (p RetryPolicy) without(statuses ...int) RetryPolicy {
+	p.RetryStatuses = slices.DeleteFunc(slices.Clone(p.RetryStatuses), func(status int) bool {
+		return slices.Contains(statuses, status)
+	})
+	return p
+}
+
+// blockStatuses are the answers of a backend refusing us, retrying
+// them only makes the block last longer
+var blockStatuses = []int{http.StatusTooManyRequests, http.StatusForbidden}
+
+// delay returns the wait before the given retry, counting from 1
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay << (retry - 1)
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// retryTransport repeats requests failing with transport errors or
+// retryable statuses. Waits honor Retry-After and never outlast the
+// deadline of the request context, the last response is returned as it is.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attempt := req
+	for try := 1; ; try++ {
+		resp, err := t.next.RoundTrip(attempt)
+		if try >= t.policy.MaxAttempts || ctx.Err() != nil || !t.retryable(resp, err) {
+			return resp, err
+		}
+		// A request body can only be sent again if it can be recreated
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+		wait := t.policy.delay(try)
+		if resp != nil {
+			if ra := parseRetryAfter(resp.Header.Get("Retry-After")); ra > 0 {
+				wait = ra
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// Waiting would run out the caller's time anyway
+			return resp, err
+		}
+		if resp != nil {
+			// Drain a little so the connection can be reused
+			_, _ = io.CopyN(io.Discard, resp.Body, 4<<10)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		attempt = req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+	}
+}
+
+// retryable reports whether the outcome of a try is worth another one
+func (t *retryTransport) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return slices.Contains(t.policy.RetryStatuses, resp.StatusCode)
+}
@@ -0,0 +1,132 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers tries with the given statuses in order,
+// a zero status fails the try with a transport error
+type scriptedTransport struct {
+	statuses   []int
+	retryAfter string
+	tries      int
+	bodies     []string
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := s.statuses[min(s.tries, len(s.statuses)-1)]
+	s.tries++
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		s.bodies = append(s.bodies, string(body))
+	}
+	if status == 0 {
+		return nil, errors.New("connection reset")
+	}
+	header := http.Header{}
+	if s.retryAfter != "" {
+		header.Set("Retry-After", s.retryAfter)
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestRetryTransport(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, RetryStatuses: DefaultRetryPolicy.RetryStatuses}
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		policy     RetryPolicy
+		timeout    time.Duration
+		wantStatus int
+		wantErr    bool
+		wantTries  int
+	}{
+		{"success", []int{200}, "", policy, 0, 200, false, 1},
+		{"retried status", []int{503, 200}, "", policy, 0, 200, false, 2},
+		{"gives up after max attempts", []int{503}, "", policy, 0, 503, false, 3},
+		{"other status is final", []int{404}, "", policy, 0, 404, false, 1},
+		{"transport error", []int{0, 200}, "", policy, 0, 200, false, 2},
+		{"transport error every try", []int{0}, "", policy, 0, 0, true, 3},
+		{"block statuses left out", []int{429}, "", policy.without(blockStatuses...), 0, 429, false, 1},
+		{"single attempt", []int{503}, "", RetryPolicy{MaxAttempts: 1, RetryStatuses: policy.RetryStatuses}, 0, 503, false, 1},
+		{"retry after past the deadline", []int{429, 200}, "60", policy, time.Second, 429, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &scriptedTransport{statuses: tt.statuses, retryAfter: tt.retryAfter}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			req, _ := http.NewRequestWithContext(ctx, "GET", "https://example.com/", nil)
+			resp, err := (&retryTransport{next: next, policy: tt.policy}).RoundTrip(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RoundTrip error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if next.tries != tt.wantTries {
+				t.Errorf("tries = %d, want %d", next.tries, tt.wantTries)
+			}
+		})
+	}
+}
+
+func TestRetryTransportBody(t *testing.T) {
+	next := &scriptedTransport{statuses: []int{503, 200}}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, RetryStatuses: []int{503}}
+	req, _ := http.NewRequest("POST", "https://example.com/", strings.NewReader("q=go"))
+	if _, err := (&retryTransport{next: next, policy: policy}).RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if !slices.Equal(next.bodies, []string{"q=go", "q=go"}) {
+		t.Errorf("bodies sent = %q, want the form twice", next.bodies)
+	}
+}
+
+func TestRetryTransportCancel(t *testing.T) {
+	next := &scriptedTransport{statuses: []int{503}}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour, RetryStatuses: []int{503}}
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://example.com/", nil)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := (&retryTransport{next: next, policy: policy}).RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip = %v, want context.Canceled", err)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{70, time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.delay(tt.retry); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.retry, got, tt.want)
+		}
+	}
+	p.Jitter = 0.5
+	for range 100 {
+		if d := p.delay(2); d < 100*time.Millisecond || d > 200*time.Millisecond {
+			t.Fatalf("delay with jitter = %v, want within 100ms..200ms", d)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package searcher
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsAgent is the product token matched against User-agent lines
+	robotsAgent    = "searchagent"
+	robotsCacheTTL = 24 * time.Hour
+	// robotsErrorTTL is how long a failed fetch of robots.txt is remembered
+	robotsErrorTTL = 10 * time.Minute
+	// robotsMaxBytes follows the limit google applies to robots.txt
+	robotsMaxBytes = 500 << 10
+)
+
+// ErrRobotsDisallowed is returned for pages robots.txt forbids to fetch
+var ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+// robotsRule is an Allow or Disallow line
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules are the rules of the group that applies to us
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// allowAll and disallowAll stand in when robots.txt is missing or broken
+var (
+	allowAll    = &robotsRules{}
+	disallowAll = &robotsRules{rules: []robotsRule{{pattern: "/"}}}
+)
+
+// allowed applies the most specific matching rule, Allow wins ties
+func (r *robotsRules) allowed(path string) bool {
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		n := len(rule.pattern)
+		if n > best || (n == best && rule.allow) {
+			best = n
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches a path against a pattern with '*' wildcards
+// and a '$' end anchor
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// parseRobots reads the group for our agent, falling back to the '*' group
+func parseRobots(r io.Reader) *robotsRules {
+	var ours, wildcard *robotsRules
+	var current []*robotsRules
+	inAgents := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				// A new group starts
+				current = nil
+				inAgents = true
+			}
+			agent := strings.ToLower(value)
+			switch {
+			case strings.Contains(agent, robotsAgent):
+				if ours == nil {
+					ours = &robotsRules{}
+				}
+				current = append(current, ours)
+			case agent == "*":
+				if wildcard == nil {
+					wildcard = &robotsRules{}
+				}
+				current = append(current, wildcard)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				// An empty Disallow allows everything
+				continue
+			}
+			for _, group := range current {
+				group.rules = append(group.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			inAgents = false
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil || secs <= 0 {
+				continue
+			}
+			for _, group := range current {
+				group.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		default:
+			inAgents = false
+		}
+	}
+	switch {
+	case ours != nil:
+		return ours
+	case wildcard != nil:
+		return wildcard
+	default:
+		return allowAll
+	}
+}
+
+// robotsEntry is the cached robots.txt of a host, ready is closed
+// once rules are fetched. Rules stay nil when the fetching caller gave up.
+type robotsEntry struct {
+	ready   chan struct{}
+	rules   *robotsRules
+	expires time.Time
+}
+
+// RobotsChecker fetches and caches robots.txt per host and tells
+// whether a page may be fetched. Crawl delays slow the host down
+// in the limiter.
+type RobotsChecker struct {
+	client  *http.Client
+	limiter *HostLimiter
+	mu      sync.Mutex
+	hosts   map[string]*robotsEntry
+	// lastSweep is when expired hosts were last dropped
+	lastSweep time.Time
+}
+
+// NewRobotsChecker creates a checker, a nil limiter ignores crawl delays
+func NewRobotsChecker(client *http.Client, limiter *HostLimiter) *RobotsChecker {
+	return &RobotsChecker{
+		client:    client,
+		limiter:   limiter,
+		hosts:     make(map[string]*robotsEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allowed reports whether robots.txt of the page's host allows fetching it
+func (c *RobotsChecker) Allowed(ctx context.Context, pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	rules := c.rules(ctx, u)
+	// Applied on every check, the limiter may have dropped the host since
+	if c.limiter != nil && rules.crawlDelay > 0 {
+		c.limiter.SetMinInterval(u.Hostname(), rules.crawlDelay)
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return rules.allowed(path)
+}
+
+// rules returns the cached rules of the host, fetching them once
+// for concurrent callers. When the fetching caller gives up, the
+// callers waiting for it fetch again.
+func (c *RobotsChecker) rules(ctx context.Context, u *url.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+	for {
+		c.mu.Lock()
+		entry, ok := c.hosts[key]
+		// rules and expires are only safe to read once ready is closed
+		if ok && isClosed(entry.ready) && (entry.rules == nil || time.Now().After(entry.expires)) {
+			ok = false
+		}
+		if !ok {
+			c.sweep()
+			entry = &robotsEntry{ready: make(chan struct{})}
+			c.hosts[key] = entry
+			c.mu.Unlock()
+			rules, ttl := c.fetch(ctx, key)
+			if ttl > 0 {
+				entry.rules = rules
+				entry.expires = time.Now().Add(ttl)
+			}
+			close(entry.ready)
+			return rules
+		}
+		c.mu.Unlock()
+		select {
+		case <-entry.ready:
+			if entry.rules != nil {
+				return entry.rules
+			}
+		case <-ctx.Done():
+			return disallowAll
+		}
+	}
+}
+
+// sweep drops expired hosts now and then, pages point to countless
+// hosts that are fetched once. The caller holds mu.
+func (c *RobotsChecker) sweep() {
+	now := time.Now()
+	if now.Sub(c.lastSweep) < robotsErrorTTL {
+		return
+	}
+	for key, entry := range c.hosts {
+		if isClosed(entry.ready) && (entry.rules == nil || now.After(entry.expires)) {
+			delete(c.hosts, key)
+		}
+	}
+	c.lastSweep = now
+}
+
+// fetch downloads robots.txt of the origin and returns the rules with
+// their lifetime. A missing file allows everything, server errors and
+// unreachable hosts disallow everything for a short while.
+func (c *RobotsChecker) fetch(ctx context.Context, origin string) (*robotsRules, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll, robotsErrorTTL
+	}
+	req.Header.Set("User-Agent", "SearchAgent/1.0")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			// The caller gave up, let the next one try again
+			return disallowAll, 0
+		}
+		return disallowAll, robotsErrorTTL
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return disallowAll, robotsErrorTTL
+	case resp.StatusCode != http.StatusOK:
+		return allowAll, robotsCacheTTL
+	}
+	return parseRobots(io.LimitReader(resp.Body, robotsMaxBytes)), robotsCacheTTL
+}
+
+// isClosed reports whether the channel is closed
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package searcher
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/anything", true},
+		{"/private", "/private/page", true},
+		{"/private", "/public", false},
+		{"/*.pdf", "/docs/file.pdf", true},
+		{"/*.pdf", "/docs/file.pdf?x=1", true},
+		{"/*.pdf$", "/docs/file.pdf", true},
+		{"/*.pdf$", "/docs/file.pdf?x=1", false},
+		{"/page$", "/page", true},
+		{"/page$", "/page/sub", false},
+		{"/a*b*c", "/a-x-b-y-c-z", true},
+		{"/a*b*c", "/a-x-c-y-b", false},
+		{"*/search", "/en/search?q=1", true},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name  string
+		txt   string
+		path  string
+		want  bool
+		delay time.Duration
+	}{
+		{"empty file", "", "/page", true, 0},
+		{"wildcard group", "User-agent: *\nDisallow: /private\n", "/private/x", false, 0},
+		{"empty disallow", "User-agent: *\nDisallow:\n", "/private/x", true, 0},
+		{"our group wins", "User-agent: *\nDisallow: /\n\nUser-agent: SearchAgent\nDisallow: /admin\n", "/page", true, 0},
+		{"shared group", "User-agent: other\nUser-agent: searchagent\nDisallow: /page\n", "/page", false, 0},
+		{"other agents only", "User-agent: googlebot\nDisallow: /\n", "/page", true, 0},
+		{"longest rule wins", "User-agent: *\nDisallow: /docs\nAllow: /docs/public\n", "/docs/public/a", true, 0},
+		{"allow wins ties", "User-agent: *\nDisallow: /page\nAllow: /page\n", "/page", true, 0},
+		{"comments", "User-agent: * # all\nDisallow: /x # not x\n", "/x", false, 0},
+		{"crawl delay", "User-agent: *\nCrawl-delay: 2.5\nDisallow: /x\n", "/page", true, 2500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(tt.txt))
+			if got := rules.allowed(tt.path); got != tt.want {
+				t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+			if rules.crawlDelay != tt.delay {
+				t.Errorf("crawlDelay = %v, want %v", rules.crawlDelay, tt.delay)
+			}
+		})
+	}
+}
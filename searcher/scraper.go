@@ -1,12 +1,19 @@
 package searcher
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
 	"golang.org/x/net/html"
@@ -14,111 +21,555 @@ import (
 
 // Constants for content limits
 const (
-	nodeLimit    = 1000  // Character limit for node content
-	contentLimit = 4000  // Character limit for extracted content
+	nodeLimit    = 1000 // Character limit for node content
+	contentLimit = 4000 // Character limit for extracted content
+)
+
+// duckDuckGoBackend names DuckDuckGo in errors
+const duckDuckGoBackend = "duckduckgo"
+
+// ErrUnsupportedFilter is returned by the scraper for engines and categories
+// only SearXNG knows, a fallback chain moves on to a searcher that does
+var ErrUnsupportedFilter = errors.New("filter not supported by the scraper")
+
+// Defaults for fetching result pages
+const (
+	defaultFetchConcurrency = 4
+	defaultFetchDeadline    = 20 * time.Second
 )
 
 // WebScraper implements the Searcher interface using web scraping
 type WebScraper struct {
 	client  *http.Client
 	baseURL string
+	// newsURL serves DuckDuckGo's news vertical
+	newsURL string
+	// searchClient queries DuckDuckGo, it must not retry blocks
+	// so the cooldown trips on the first one
+	searchClient *http.Client
+	// concurrency is the number of result pages fetched at once
+	concurrency int
+	// fetchDeadline bounds fetching of all result pages of one search
+	fetchDeadline time.Duration
+	// maxBody caps the bytes read of every response
+	maxBody int64
+	// pageCache keeps extracted content by page url, nil disables it
+	pageCache *lruCache[pageContent]
+	// diskCache persists fetched pages between restarts, nil disables it
+	diskCache *diskPageCache
+	// diskCacheFresh is the age until which a persisted page is used
+	// without revalidating it
+	diskCacheFresh time.Duration
+	// extractMode selects the part of a page kept as content
+	extractMode ExtractMode
+	// robots skips pages disallowed by robots.txt, nil disables it
+	robots *RobotsChecker
+	// blocked backs off DuckDuckGo after it showed a captcha or rate limited us
+	blocked *cooldown
 }
 
-func NewWebScraper(url string) *WebScraper {
+// NewWebScraper creates a scraper for the given DuckDuckGo html endpoint.
+// A nil client gets replaced with a standalone one.
+func NewWebScraper(url string, client *http.Client) *WebScraper {
+	if url == "" {
+		url = defaultScraperURL
+	}
+	if client == nil {
+		client = NewHTTPClient(defaultTimeout, DefaultRetryPolicy, nil)
+	}
 	return &WebScraper{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		baseURL: url,
+		client:        client,
+		searchClient:  NewHTTPClient(client.Timeout, DefaultRetryPolicy.without(blockStatuses...), nil),
+		baseURL:       url,
+		newsURL:       duckDuckGoNewsURL,
+		concurrency:   defaultFetchConcurrency,
+		fetchDeadline: defaultFetchDeadline,
+		extractMode:   ExtractMain,
+		maxBody:       defaultMaxBodyBytes,
+		blocked:       &cooldown{duration: defaultBlockCooldown},
+	}
+}
+
+// pageContent is the extracted content of a result page
+type pageContent struct {
+	content string
+	meta    pageMeta
+	// truncated is set when the page was cut at the body limit
+	truncated bool
+	// finalURL is where redirects led
+	finalURL string
+	// status is the response code of the fetch, 304 when a cached
+	// copy was revalidated
+	status int
+	// length is the size of the document in bytes
+	length int64
+}
+
+// apply copies the page's content and metadata into the result, a page
+// without text keeps the snippet the result came with
+func (p pageContent) apply(result *SearchResult) {
+	if p.content != "" {
+		result.Content = p.content
+	}
+	result.FinalURL = p.finalURL
+	result.FetchStatus = p.status
+	result.ContentLength = p.length
+	if p.meta.Author != "" {
+		result.Author = p.meta.Author
+	}
+	if p.meta.SiteName != "" {
+		result.SiteName = p.meta.SiteName
+	}
+	if p.meta.Language != "" {
+		result.Language = p.meta.Language
+	}
+	if p.meta.CanonicalURL != "" {
+		result.CanonicalURL = p.meta.CanonicalURL
+	}
+	if p.meta.Published != nil && result.PublishedDate == nil {
+		result.PublishedDate = p.meta.Published
+	}
+	result.Metadata = p.meta.Data
+	if p.truncated {
+		result.Flags = append(result.Flags, FlagTruncated)
+	}
+}
+
+// SetSearchClient sets the client querying DuckDuckGo, its retry policy
+// should leave out blockStatuses
+func (ws *WebScraper) SetSearchClient(client *http.Client) {
+	if client != nil {
+		ws.searchClient = client
 	}
 }
 
-func (ws *WebScraper) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+// SetBlockCooldown sets how long DuckDuckGo is left alone
+// after it blocked a search, unless it asks for a longer wait
+func (ws *WebScraper) SetBlockCooldown(d time.Duration) {
+	if d > 0 {
+		ws.blocked.duration = d
+	}
+}
+
+// SetPageCache keeps extracted content of up to size pages for ttl,
+// so pages showing up in several searches are fetched once
+func (ws *WebScraper) SetPageCache(size int, ttl time.Duration) {
+	ws.pageCache = newLRUCache[pageContent](size, ttl)
+}
+
+// SetMaxBodyBytes caps the bytes read of a response, longer result pages
+// are extracted from their beginning and flagged as truncated
+func (ws *WebScraper) SetMaxBodyBytes(n int64) {
+	if n > 0 {
+		ws.maxBody = n
+	}
+}
+
+// SetDiskCache persists fetched pages in dir, up to maxBytes.
+// Pages younger than fresh are used as they are, older ones are
+// revalidated with a conditional request.
+func (ws *WebScraper) SetDiskCache(dir string, maxBytes int64, fresh time.Duration) error {
+	cache, err := newDiskPageCache(dir, maxBytes)
+	if err != nil {
+		return err
+	}
+	ws.diskCache = cache
+	ws.diskCacheFresh = fresh
+	return nil
+}
+
+// SetExtractMode selects whether the main content block or the whole
+// page becomes the content of a result
+func (ws *WebScraper) SetExtractMode(mode ExtractMode) {
+	ws.extractMode = mode
+}
+
+// SetRobotsChecker makes the scraper skip pages robots.txt disallows
+func (ws *WebScraper) SetRobotsChecker(robots *RobotsChecker) {
+	ws.robots = robots
+}
+
+// SetFetchLimits sets how many result pages are fetched at once and the
+// overall deadline for fetching them. Non-positive values keep the current ones.
+func (ws *WebScraper) SetFetchLimits(concurrency int, deadline time.Duration) {
+	if concurrency > 0 {
+		ws.concurrency = concurrency
+	}
+	if deadline > 0 {
+		ws.fetchDeadline = deadline
+	}
+}
+
+// scraperFilters checks that DuckDuckGo can honor the engines and
+// categories of a search, it has its web results and news only
+func scraperFilters(opts SearchOptions) error {
+	if len(opts.Engines) > 0 {
+		return fmt.Errorf("%w: engines %s", ErrUnsupportedFilter, strings.Join(opts.Engines, ","))
+	}
+	for _, category := range opts.Categories {
+		if category != "general" && category != CategoryNews {
+			return fmt.Errorf("%w: category %s", ErrUnsupportedFilter, category)
+		}
+	}
+	return nil
+}
+
+func (ws *WebScraper) Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	if err := scraperFilters(opts); err != nil {
+		return nil, err
+	}
+	if slices.Contains(opts.Categories, CategoryNews) {
+		return ws.searchNews(ctx, query, limit, opts)
+	}
 	// Attempt to perform a real search using Google Custom Search or similar
 	// Since we don't have an API key in this implementation, let's use a basic technique
 	// that searches and extracts results from HTML
-	results := make([]SearchResult, 0, limit)
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	skip, err := cursorInt(cur, cursorSkip, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Pages after the first one are requested by submitting DuckDuckGo's "next" form
+	form, err := url.ParseQuery(cur.Get("form"))
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
 	// For now, let's implement a basic search that uses DuckDuckGo HTML search
 	// which doesn't require an API key but is subject to rate limits and may break
 	// if DuckDuckGo changes their HTML structure
-	searchResults, err := ws.searchDuckDuckGo(ctx, query, limit)
+	page, err := ws.searchDuckDuckGo(ctx, query, opts, cur.Get("action"), form)
 	if err != nil {
 		return nil, err
 	}
-	// If no results found, return empty results
-	if len(searchResults) == 0 {
-		return []SearchResult{}, nil
+	taken, nextSkip, more := takePage(page.results, skip, limit)
+	results := make([]SearchResult, 0, len(taken))
+	results = append(results, taken...)
+	// Extract content for each URL
+	ws.fetchContents(ctx, results, opts.Format)
+	next := url.Values{}
+	switch {
+	case more:
+		// Continue on the same page
+		next.Set("action", cur.Get("action"))
+		next.Set("form", cur.Get("form"))
+		next.Set(cursorSkip, strconv.Itoa(nextSkip))
+	case page.nextForm != nil:
+		next.Set("action", page.nextAction)
+		next.Set("form", page.nextForm.Encode())
 	}
-	for i, result := range searchResults {
-		if i >= limit {
-			break
-		}
-		results = append(results, result)
+	var nextCursor string
+	if len(next) > 0 {
+		nextCursor = encodeCursor(next)
 	}
-	return results, nil
+	return &ResultPage{
+		Results:    results,
+		NextCursor: nextCursor,
+	}, nil
 }
 
-// searchDuckDuckGo performs a real search on DuckDuckGo and extracts results
-func (ws *WebScraper) searchDuckDuckGo(ctx context.Context, query string, limit int) ([]SearchResult, error) {
-	// Encode the query for URL
-	encodedQuery := strings.ReplaceAll(query, " ", "+")
-	searchURL := ws.baseURL + encodedQuery
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+// duckDuckGoPage is a parsed page of DuckDuckGo results
+type duckDuckGoPage struct {
+	results []SearchResult
+	// nextAction and nextForm submit the "next" form, nil form on the last page
+	nextAction string
+	nextForm   url.Values
+}
+
+// searchDuckDuckGo performs a real search on DuckDuckGo and extracts results.
+// A non-empty form is posted to action to get a following page.
+func (ws *WebScraper) searchDuckDuckGo(ctx context.Context, query string, opts SearchOptions, action string, form url.Values) (*duckDuckGoPage, error) {
+	var req *http.Request
+	var err error
+	if len(form) == 0 {
+		req, err = ws.firstPageRequest(ctx, query, opts)
+	} else {
+		req, err = ws.nextPageRequest(ctx, action, form)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, doc, err := ws.doDuckDuckGo(req)
 	if err != nil {
 		return nil, err
 	}
+	if doc == nil {
+		return nil, &BackendError{Backend: duckDuckGoBackend, Err: fmt.Errorf("%w: not an html page", ErrInvalidResponse)}
+	}
+	// Parse the HTML to extract search results
+	page := &duckDuckGoPage{results: ws.parseDuckDuckGoResults(doc)}
+	page.nextAction, page.nextForm = ws.findNextForm(doc)
+	return page, nil
+}
+
+// doDuckDuckGo sends a request to DuckDuckGo and returns the body, parsed
+// as well when it is an HTML page. Blocks, rate limits and bot challenges
+// back off every DuckDuckGo request for a while.
+func (ws *WebScraper) doDuckDuckGo(req *http.Request) ([]byte, *html.Node, error) {
+	// Don't provoke a ban by searching while backed off
+	if wait := ws.blocked.remaining(); wait > 0 {
+		return nil, nil, &BackendError{Backend: duckDuckGoBackend, RetryAfter: wait, Err: ErrBlocked}
+	}
 	// Add user agent and referer headers to avoid being blocked
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
 	req.Header.Set("Referer", "https://duckduckgo.com/")
-	resp, err := ws.client.Do(req)
+	resp, err := ws.searchClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, transportError(duckDuckGoBackend, err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code error: %d", resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusAccepted:
+		// DuckDuckGo answers bots with 202 and a challenge page
+		return nil, nil, ws.blockError(resp)
+	default:
+		err := statusError(duckDuckGoBackend, resp)
+		if errors.Is(err, ErrBlocked) || errors.Is(err, ErrRateLimited) {
+			ws.blocked.trip(err)
+		}
+		return nil, nil, err
+	}
+	body, err := io.ReadAll(newCappedReader(resp.Body, ws.maxBody))
+	if err != nil {
+		return nil, nil, transportError(duckDuckGoBackend, err)
+	}
+	if contentKind(resp.Header.Get("Content-Type"), body) != kindHTML {
+		return body, nil, nil
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, &BackendError{Backend: duckDuckGoBackend, Err: fmt.Errorf("%w: %w", ErrInvalidResponse, err)}
+	}
+	if ws.isAnomalyPage(doc) {
+		return nil, nil, ws.blockError(resp)
+	}
+	return body, doc, nil
+}
+
+// blockError backs off DuckDuckGo after it served a bot challenge
+func (ws *WebScraper) blockError(resp *http.Response) error {
+	err := &BackendError{
+		Backend:    duckDuckGoBackend,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Err:        ErrBlocked,
+	}
+	ws.blocked.trip(err)
+	return err
+}
+
+// anomalyMarkers are ids and classes of DuckDuckGo's bot challenge
+var anomalyMarkers = []string{"anomaly-modal", "anomaly_modal", "challenge-form"}
+
+// isAnomalyPage reports whether DuckDuckGo served its bot challenge
+// instead of results
+func (ws *WebScraper) isAnomalyPage(doc *html.Node) bool {
+	var found bool
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if found {
+			return
+		}
+		switch n.Type {
+		case html.ElementNode:
+			id := ws.getAttr(n, "id")
+			for _, marker := range anomalyMarkers {
+				if strings.Contains(id, marker) || ws.hasClassPrefix(n, marker) {
+					found = true
+					return
+				}
+			}
+		case html.TextNode:
+			if strings.Contains(n.Data, "bots use DuckDuckGo too") {
+				found = true
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return found
+}
+
+// firstPageRequest builds the GET request for the first page of results
+func (ws *WebScraper) firstPageRequest(ctx context.Context, query string, opts SearchOptions) (*http.Request, error) {
+	// Encode the query for URL
+	searchURL := ws.baseURL + url.QueryEscape(query)
+	params := url.Values{}
+	if kl := opts.duckDuckGoLocale(); kl != "" {
+		params.Set("kl", kl)
 	}
-	body, err := io.ReadAll(resp.Body)
+	if df := opts.duckDuckGoTime(); df != "" {
+		params.Set("df", df)
+	}
+	if kp := opts.duckDuckGoSafeSearch(); kp != "" {
+		params.Set("kp", kp)
+	}
+	if len(params) > 0 {
+		searchURL += "&" + params.Encode()
+	}
+	return http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+}
+
+// nextPageRequest builds the POST request submitting a "next" form.
+// The action comes from a client supplied cursor, so only its path is used
+// and the request always goes to the configured DuckDuckGo host.
+func (ws *WebScraper) nextPageRequest(ctx context.Context, action string, form url.Values) (*http.Request, error) {
+	base, err := url.Parse(ws.baseURL)
 	if err != nil {
 		return nil, err
 	}
-	// Parse the HTML to extract search results
-	results := ws.parseDuckDuckGoResults(string(body), limit)
-	// Extract content for each URL
-	for i := range results {
-		content, err := ws.extractContentFromURL(ctx, results[i].URL)
+	path := "/html/"
+	if action != "" {
+		u, err := url.Parse(action)
 		if err != nil {
-			// If we can't fetch content, keep the existing content
+			return nil, ErrInvalidCursor
+		}
+		path = u.Path
+	}
+	target := base.ResolveReference(&url.URL{Path: path})
+	req, err := http.NewRequestWithContext(ctx, "POST", target.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// findNextForm returns the action and hidden fields of the "next" form
+// in the page navigation, the form is nil on the last page
+func (ws *WebScraper) findNextForm(doc *html.Node) (string, url.Values) {
+	var action string
+	var form url.Values
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if form != nil {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "form" && ws.isNextForm(n) {
+			action = ws.getAttr(n, "action")
+			form = url.Values{}
+			var collect func(*html.Node)
+			collect = func(n *html.Node) {
+				if n.Type == html.ElementNode && n.Data == "input" && ws.getAttr(n, "type") == "hidden" {
+					form.Add(ws.getAttr(n, "name"), ws.getAttr(n, "value"))
+				}
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					collect(c)
+				}
+			}
+			collect(n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return action, form
+}
+
+// isNextForm checks whether the form is submitted by a "Next" button,
+// the navigation also has a "Previous" form
+func (ws *WebScraper) isNextForm(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
 			continue
 		}
-		results[i].Content = content
+		if c.Data == "input" && ws.getAttr(c, "type") == "submit" {
+			return strings.EqualFold(strings.TrimSpace(ws.getAttr(c, "value")), "next")
+		}
+		if ws.isNextForm(c) {
+			return true
+		}
 	}
-	return results, nil
+	return false
 }
 
-// parseDuckDuckGoResults parses DuckDuckGo HTML results to extract search snippets
-func (ws *WebScraper) parseDuckDuckGoResults(htmlContent string, limit int) []SearchResult {
-	doc, err := html.Parse(strings.NewReader(htmlContent))
-	if err != nil {
-		return []SearchResult{} // Return empty results if parsing fails
+// getAttr returns the value of the attribute or an empty string
+func (ws *WebScraper) getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
 	}
+	return ""
+}
+
+// fetchContents replaces snippets with page content using a bounded pool of
+// workers. Results keep their order, pages that fail or miss the deadline
+// keep their snippet.
+func (ws *WebScraper) fetchContents(ctx context.Context, results []SearchResult, format ContentFormat) {
+	if len(results) == 0 {
+		return
+	}
+	if format == "" {
+		format = FormatText
+	}
+	ctx, cancel := context.WithTimeout(ctx, ws.fetchDeadline)
+	defer cancel()
+	workers := min(ws.concurrency, len(results))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			// every worker writes only to the index it received
+			for i := range jobs {
+				page, err := ws.extractContentFromURL(ctx, results[i].URL, format)
+				if errors.Is(err, ErrRobotsDisallowed) {
+					results[i].Flags = append(results[i].Flags, FlagRobotsDisallowed)
+					continue
+				}
+				if errors.Is(err, ErrBinaryContent) {
+					results[i].Flags = append(results[i].Flags, FlagBinaryContent)
+					continue
+				}
+				if err != nil {
+					// If we can't fetch content, keep the existing content
+					var backendErr *BackendError
+					if errors.As(err, &backendErr) {
+						results[i].FetchStatus = backendErr.StatusCode
+					}
+					continue
+				}
+				page.apply(&results[i])
+			}
+		})
+	}
+feed:
+	for i := range results {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// parseDuckDuckGoResults parses DuckDuckGo HTML results to extract search snippets
+func (ws *WebScraper) parseDuckDuckGoResults(doc *html.Node) []SearchResult {
 	var results []SearchResult
 	var parse func(*html.Node)
 	parse = func(n *html.Node) {
 		if n.Type == html.ElementNode && n.Data == "div" {
 			// Check if this is a search result container
-			if ws.hasClass(n, "result") {
+			// Sponsored results are marked with 'result--ad'
+			if ws.hasClass(n, "result") && !ws.hasClass(n, "result--ad") {
 				result := ws.extractResultFromNode(n)
 				if result.URL != "" && result.Title != "" { // Only add if both URL and Title are present
 					results = append(results, result)
-					if len(results) >= limit {
-						return
-					}
 				}
 			}
 		}
 		// Continue traversing children and siblings
-		for c := n.FirstChild; c != nil && len(results) < limit; c = c.NextSibling {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
 			parse(c)
 		}
 	}
@@ -129,6 +580,7 @@ func (ws *WebScraper) parseDuckDuckGoResults(htmlContent string, limit int) []Se
 // extractResultFromNode extracts the title, URL and content from a search result node
 func (ws *WebScraper) extractResultFromNode(n *html.Node) SearchResult {
 	var result SearchResult
+	var displayURL string
 	var find func(*html.Node)
 	find = func(n *html.Node) {
 		if n.Type == html.ElementNode {
@@ -149,11 +601,7 @@ func (ws *WebScraper) extractResultFromNode(n *html.Node) SearchResult {
 			}
 			// Look for the URL text (sometimes available in separate element)
 			if n.Data == "a" && ws.hasClass(n, "result__url") {
-				if result.URL == "" {
-					// This is a fallback - in a real implementation we'd extract the URL from the href
-					// For now we'll just use the text content as a placeholder
-					result.URL = ws.getTextContent(n)
-				}
+				displayURL = ws.getTextContent(n)
 			}
 		}
 		// Recursively look in children
@@ -162,6 +610,18 @@ func (ws *WebScraper) extractResultFromNode(n *html.Node) SearchResult {
 		}
 	}
 	find(n)
+	if result.URL != "" {
+		var ok bool
+		result.URL, ok = resolveDuckDuckGoURL(result.URL)
+		if !ok {
+			// Ads and unknown duckduckgo links are dropped
+			return SearchResult{}
+		}
+	}
+	if result.URL == "" && displayURL != "" {
+		// The displayed url lacks the scheme, e.g. 'www.example.com/page'
+		result.URL = absoluteHTTPURL("https://" + strings.ReplaceAll(displayURL, " ", ""))
+	}
 	// If content is still empty, extract any relevant text from the description
 	if result.Content == "" {
 		var extractDesc func(*html.Node)
@@ -185,9 +645,53 @@ func (ws *WebScraper) extractResultFromNode(n *html.Node) SearchResult {
 	if len(result.Content) > nodeLimit { // Limit content length
 		result.Content = result.Content[:nodeLimit] + "..."
 	}
+	result.Snippet = result.Content
+	result.Engines = []string{duckDuckGoBackend}
 	return result
 }
 
+// resolveDuckDuckGoURL turns a result href into the real destination url.
+// DuckDuckGo wraps results into '//duckduckgo.com/l/?uddg=<escaped url>'
+// redirects and ads into '/y.js' links; ok is false for links that
+// don't lead to a result page.
+func resolveDuckDuckGoURL(href string) (string, bool) {
+	href = strings.TrimSpace(href)
+	if strings.HasPrefix(href, "//") {
+		href = "https:" + href
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if u.Host == "" || host == "duckduckgo.com" || strings.HasSuffix(host, ".duckduckgo.com") {
+		switch u.Path {
+		case "/l/", "/l":
+			// Query() already unescapes the value
+			target := absoluteHTTPURL(u.Query().Get("uddg"))
+			return target, target != ""
+		default:
+			// '/y.js' ads and internal links
+			return "", false
+		}
+	}
+	target := absoluteHTTPURL(href)
+	return target, target != ""
+}
+
+// absoluteHTTPURL returns the url if it is an absolute http(s) url
+// and an empty string otherwise
+func absoluteHTTPURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
 // hasClass checks if an HTML node has a specific class
 func (ws *WebScraper) hasClass(n *html.Node, class string) bool {
 	for _, attr := range n.Attr {
@@ -203,6 +707,16 @@ func (ws *WebScraper) hasClass(n *html.Node, class string) bool {
 	return false
 }
 
+// hasClassPrefix checks if any class of an HTML node starts with prefix
+func (ws *WebScraper) hasClassPrefix(n *html.Node, prefix string) bool {
+	for _, c := range strings.Fields(ws.getAttr(n, "class")) {
+		if strings.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // getTextContent extracts text content from an HTML node
 func (ws *WebScraper) getTextContent(n *html.Node) string {
 	var text string
@@ -219,79 +733,239 @@ func (ws *WebScraper) getTextContent(n *html.Node) string {
 	return strings.TrimSpace(text)
 }
 
-// extractContentFromURL fetches and extracts meaningful text content from a webpage
-func (ws *WebScraper) extractContentFromURL(ctx context.Context, pageURL string) (string, error) {
+// extractContentFromURL fetches and extracts meaningful content from a webpage
+// in the given format
+func (ws *WebScraper) extractContentFromURL(ctx context.Context, pageURL string, format ContentFormat) (pageContent, error) {
+	if ws.robots != nil && !ws.robots.Allowed(ctx, pageURL) {
+		if err := ctx.Err(); err != nil {
+			return pageContent{}, err
+		}
+		return pageContent{}, ErrRobotsDisallowed
+	}
+	cacheKey := string(format) + "\x00" + pageURL
+	if ws.pageCache != nil {
+		if page, ok := ws.pageCache.Get(cacheKey); ok {
+			return page, nil
+		}
+	}
+	var stored *diskEntry
+	if ws.diskCache != nil {
+		stored = ws.diskCache.Get(pageURL)
+		if stored != nil && time.Since(stored.FetchedAt) < ws.diskCacheFresh {
+			page, err := ws.storedContent(stored, format)
+			if err != nil {
+				return pageContent{}, err
+			}
+			ws.cacheContent(cacheKey, page)
+			return page, nil
+		}
+	}
 	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
 	if err != nil {
-		return "", err
+		return pageContent{}, err
 	}
 	// Add a user agent to avoid being blocked by some sites
 	req.Header.Set("User-Agent", "SearchAgent/1.0")
+	if stored != nil {
+		// Let the site answer 304 if the page didn't change
+		if stored.ETag != "" {
+			req.Header.Set("If-None-Match", stored.ETag)
+		}
+		if stored.LastModified != "" {
+			req.Header.Set("If-Modified-Since", stored.LastModified)
+		}
+	}
 	resp, err := ws.client.Do(req)
 	if err != nil {
-		return "", err
+		return pageContent{}, transportError(req.URL.Host, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && stored != nil {
+		stored.FetchedAt = time.Now()
+		page, err := ws.storedContent(stored, format)
+		if err != nil {
+			return pageContent{}, err
+		}
+		page.status = resp.StatusCode
+		ws.diskCache.Put(stored)
+		ws.cacheContent(cacheKey, page)
+		return page, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("status code error: %d", resp.StatusCode)
+		return pageContent{}, statusError(req.URL.Host, resp)
 	}
-	body, err := io.ReadAll(resp.Body)
+	contentType := resp.Header.Get("Content-Type")
+	if mediaKind(contentType) == kindBinary {
+		// Not worth downloading
+		return pageContent{}, ErrBinaryContent
+	}
+	// Long pages are cut, their beginning usually holds what matters
+	body, truncated, err := readCapped(resp.Body, ws.maxBody)
+	if err != nil {
+		return pageContent{}, err
+	}
+	content, meta, err := ws.extractContent(body, contentType, pageURL, format)
+	if err != nil {
+		return pageContent{}, err
+	}
+	if meta.Language == "" {
+		meta.Language = resp.Header.Get("Content-Language")
+	}
+	page := pageContent{
+		content:   content,
+		meta:      meta,
+		truncated: truncated,
+		finalURL:  resp.Request.URL.String(),
+		status:    resp.StatusCode,
+		length:    int64(len(body)),
+	}
+	if truncated && resp.ContentLength > page.length {
+		page.length = resp.ContentLength
+	}
+	if ws.diskCache != nil {
+		ws.diskCache.Put(&diskEntry{
+			URL:          pageURL,
+			FinalURL:     page.finalURL,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			ContentType:  contentType,
+			Length:       page.length,
+			Truncated:    truncated,
+			FetchedAt:    time.Now(),
+			Body:         body,
+			Meta:         meta,
+			Format:       format,
+			Content:      content,
+		})
+	}
+	ws.cacheContent(cacheKey, page)
+	return page, nil
+}
+
+// extractContent turns a fetched document into content of the given format,
+// limited to a reasonable size
+func (ws *WebScraper) extractContent(body []byte, contentType, pageURL string, format ContentFormat) (string, pageMeta, error) {
+	content, meta, err := extractDocument(body, contentType, pageURL, ws.extractMode, format)
 	if err != nil {
-		return "", err
+		return "", pageMeta{}, err
+	}
+	return truncateContent(content, contentLimit, format), meta, nil
+}
+
+// storedContent returns the content of a page from the disk cache,
+// extracting it again from the stored body when it was kept in another format
+func (ws *WebScraper) storedContent(stored *diskEntry, format ContentFormat) (pageContent, error) {
+	if stored.Format != format {
+		content, _, err := ws.extractContent(stored.Body, stored.ContentType, stored.URL, format)
+		if err != nil {
+			return pageContent{}, err
+		}
+		stored.Content = content
+		stored.Format = format
 	}
-	// Parse the HTML and extract text content
-	content := extractTextFromHTML(string(body))
-	// Limit the content to a reasonable size
-	if len(content) > contentLimit {
-		content = content[:contentLimit]
+	return pageContent{
+		content:   stored.Content,
+		meta:      stored.Meta,
+		truncated: stored.Truncated,
+		finalURL:  stored.FinalURL,
+		status:    http.StatusOK,
+		length:    stored.Length,
+	}, nil
+}
+
+// cacheContent keeps extracted content in the in-memory page cache
+func (ws *WebScraper) cacheContent(key string, page pageContent) {
+	if ws.pageCache != nil {
+		ws.pageCache.Set(key, page)
 	}
-	return content, nil
 }
 
-// extractTextFromHTML removes HTML tags and returns text content
-// extractTextFromHTML removes HTML tags and returns text content
-func extractTextFromHTML(htmlContent string) string {
+// extractTextFromHTML removes HTML tags and returns the content in the
+// given format, the main content block only in ExtractMain mode.
+// Relative links are resolved against pageURL.
+func extractTextFromHTML(htmlContent, pageURL string, mode ExtractMode, format ContentFormat) string {
+	content, _ := extractHTML(htmlContent, pageURL, mode, format)
+	return content
+}
+
+// extractHTML is extractTextFromHTML that also returns what the page
+// tells about itself
+func extractHTML(htmlContent, pageURL string, mode ExtractMode, format ContentFormat) (string, pageMeta) {
 	// Parse the HTML document
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
-		return ""
+		return "", pageMeta{}
+	}
+	meta := readPageMeta(doc, pageURL)
+	cleanDocument(doc)
+	content := doc.Find("body")
+	if content.Length() == 0 {
+		content = doc.Selection
 	}
-	
-	// Remove unwanted elements that contain JavaScript, CSS, or other non-content
+	if mode != ExtractPage {
+		removeBoilerplate(doc)
+		if main := mainContent(doc); main != nil {
+			content = main
+		}
+	}
+	base, _ := url.Parse(pageURL)
+	if href, ok := doc.Find("base[href]").Attr("href"); ok && base != nil {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(content, base), meta
+	case FormatHTMLClean:
+		return renderCleanHTML(content, base), meta
+	default:
+		return renderText(content), meta
+	}
+}
+
+// truncateUTF8 cuts s to at most limit bytes without splitting a character
+func truncateUTF8(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
+
+// cleanDocument removes elements that contain JavaScript, CSS, or other non-content
+func cleanDocument(doc *goquery.Document) {
 	doc.Find("script").Remove()
-	doc.Find("style").Remove() 
+	doc.Find("style").Remove()
 	doc.Find("noscript").Remove()
 	doc.Find("template").Remove()
 	doc.Find("svg").Remove()
 	doc.Find("link").Remove()
 	doc.Find("meta").Remove()
-	
+
 	// Remove script elements with specific attributes
 	doc.Find("script[type]").Each(func(i int, s *goquery.Selection) {
 		if typeAttr, exists := s.Attr("type"); exists {
-			if typeAttr == "application/ld+json" || 
-			   typeAttr == "application/json" || 
-			   strings.Contains(typeAttr, "script") {
+			if typeAttr == "application/ld+json" ||
+				typeAttr == "application/json" ||
+				strings.Contains(typeAttr, "script") {
 				s.Remove()
 			}
 		}
 	})
-	
+
 	// Remove comments, which may contain scripts
 	doc.Find("comment").Remove()
-	
-	// Extract the text content from the cleaned document
-	text := doc.Text()
-	
-	// Clean up extra whitespace
-	text = strings.Join(strings.Fields(text), " ")
-	return text
-}// removeJSCSSPatterns removes obvious JavaScript and CSS content from extracted text
+}
+
+// removeJSCSSPatterns removes obvious JavaScript and CSS content from extracted text
 // This is a backup filter in case some content slips through the goquery filtering
 func removeJSCSSPatterns(text string) string {
 	// With the goquery implementation, most JavaScript/CSS should be filtered during HTML parsing
 	// However, as a backup, remove content that clearly looks like JavaScript/CSS
-	
+
 	// Remove content that looks like JSON-LD structured data
 	for {
 		startIdx := strings.Index(text, "{\"@context\"")
@@ -311,7 +985,7 @@ func removeJSCSSPatterns(text string) string {
 				}
 			}
 		}
-		
+
 		if endIdx > startIdx {
 			text = text[:startIdx] + text[endIdx:]
 		} else {
@@ -323,8 +997,8 @@ func removeJSCSSPatterns(text string) string {
 			text = text[:startIdx] + text[nextIdx:]
 		}
 	}
-	
+
 	// Clean up extra spaces after removals
 	text = strings.Join(strings.Fields(text), " ")
 	return text
-}
\ No newline at end of file
+}
@@ -0,0 +1,179 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestResolveDuckDuckGoURL(t *testing.T) {
+	tests := []struct {
+		href   string
+		want   string
+		wantOK bool
+	}{
+		{"//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F%3Fa%3D1&rut=abc", "https://go.dev/doc/?a=1", true},
+		{"https://duckduckgo.com/l/?uddg=http%3A%2F%2Fexample.com%2F", "http://example.com/", true},
+		{"/l/?uddg=https%3A%2F%2Fexample.com%2Fpage", "https://example.com/page", true},
+		{"https://duckduckgo.com/y.js?ad_provider=bing&u3=x", "", false},
+		{"/y.js?ad_domain=example.com", "", false},
+		{"https://duckduckgo.com/?q=other", "", false},
+		{"//duckduckgo.com/l/?uddg=javascript%3Aalert(1)", "", false},
+		{"https://example.com/direct", "https://example.com/direct", true},
+		{"ftp://example.com/file", "", false},
+		{"  https://example.com/spaced  ", "https://example.com/spaced", true},
+	}
+	for _, tt := range tests {
+		got, ok := resolveDuckDuckGoURL(tt.href)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("resolveDuckDuckGoURL(%q) = %q, %v, want %q, %v", tt.href, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPageContentApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    pageContent
+		content string
+	}{
+		{"fetched text replaces the snippet", pageContent{content: "page text", status: 200}, "page text"},
+		{"empty page keeps the snippet", pageContent{status: 200}, "snippet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SearchResult{Content: "snippet", Snippet: "snippet"}
+			tt.page.apply(&result)
+			if result.Content != tt.content || result.FetchStatus != tt.page.status {
+				t.Errorf("apply gave content %q and status %d, want %q and %d", result.Content, result.FetchStatus, tt.content, tt.page.status)
+			}
+		})
+	}
+}
+
+func TestScraperFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    SearchOptions
+		wantErr bool
+	}{
+		{"none", SearchOptions{}, false},
+		{"general", SearchOptions{Categories: []string{"general"}}, false},
+		{"news", SearchOptions{Categories: []string{CategoryNews}}, false},
+		{"searxng category", SearchOptions{Categories: []string{"q&a"}}, true},
+		{"engines", SearchOptions{Engines: []string{"github"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := scraperFilters(tt.opts)
+			if gotErr := errors.Is(err, ErrUnsupportedFilter); gotErr != tt.wantErr {
+				t.Errorf("scraperFilters = %v, want unsupported %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDuckDuckGoChallenge(t *testing.T) {
+	challenge := `<html><body><div class="anomaly-modal__title">Unfortunately, bots use DuckDuckGo too.</div></body></html>`
+	tests := []struct {
+		name       string
+		categories []string
+	}{
+		{"html search", nil},
+		{"news token page", []string{CategoryNews}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write([]byte(challenge))
+			}))
+			defer srv.Close()
+			ws := NewWebScraper(srv.URL+"/html/?q=", srv.Client())
+			ws.newsURL = srv.URL + "/"
+			opts := SearchOptions{Categories: tt.categories}
+			if _, err := ws.Search(context.Background(), "q", 5, opts); !errors.Is(err, ErrBlocked) {
+				t.Fatalf("Search = %v, want ErrBlocked", err)
+			}
+			if _, err := ws.Search(context.Background(), "q", 5, opts); !errors.Is(err, ErrBlocked) || RetryAfter(err) <= 0 {
+				t.Errorf("Search while backed off = %v, want ErrBlocked with a wait", err)
+			}
+			if requests != 1 {
+				t.Errorf("DuckDuckGo got %d requests, want 1", requests)
+			}
+		})
+	}
+}
+
+func TestIsAnomalyPage(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want bool
+	}{
+		{"results", `<div class="result results_links"><a class="result__a" href="https://go.dev">Go</a></div>`, false},
+		{"anomaly modal class", `<div class="anomaly-modal__mask"></div>`, true},
+		{"anomaly id", `<div id="anomaly_modal"></div>`, true},
+		{"challenge form", `<form id="challenge-form" action="/anomaly.js"></form>`, true},
+		{"challenge text", `<p>Unfortunately, bots use DuckDuckGo too.</p>`, true},
+		{"marker in a result", `<div class="result__snippet">how the anomaly detection works</div>`, false},
+	}
+	ws := NewWebScraper("", nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ws.isAnomalyPage(doc); got != tt.want {
+				t.Errorf("isAnomalyPage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuckDuckGoBlockStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		want       error
+		wantWait   time.Duration
+	}{
+		{"challenge status", http.StatusAccepted, "", ErrBlocked, defaultBlockCooldown},
+		{"rate limited", http.StatusTooManyRequests, "", ErrRateLimited, defaultBlockCooldown},
+		{"longer wait asked for", http.StatusTooManyRequests, "600", ErrRateLimited, 10 * time.Minute},
+		{"forbidden", http.StatusForbidden, "", ErrBlocked, defaultBlockCooldown},
+		{"server error", http.StatusInternalServerError, "", ErrBackendUnavailable, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			ws := NewWebScraper(srv.URL+"/html/?q=", srv.Client())
+			_, err := ws.Search(context.Background(), "q", 5, SearchOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Search = %v, want %v", err, tt.want)
+			}
+			if got := RetryAfter(err); got != tt.wantWait {
+				t.Errorf("RetryAfter = %v, want %v", got, tt.wantWait)
+			}
+			if backedOff := ws.blocked.remaining() > 0; backedOff != (tt.wantWait > 0) {
+				t.Errorf("backed off = %v, want %v", backedOff, tt.wantWait > 0)
+			}
+		})
+	}
+}
@@ -4,56 +4,310 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
-	"time"
 )
 
 // SearXNGAPISearcher implements the Searcher interface using the SearXNG API
 type SearXNGAPISearcher struct {
 	client  *http.Client
 	baseURL string
+	// name identifies the instance in errors
+	name string
+	// maxBody caps the bytes read of a response
+	maxBody int64
+	// categories and engines are used when a search names none
+	categories []string
+	engines    []string
 }
 
 // SearXNGResult represents a single search result from the SearXNG API
 type SearXNGResult struct {
-	Title         string `json:"title"`
-	URL           string `json:"url"`
-	Content       string `json:"content"`
-	Engine        string `json:"engine"`
-	PublishedDate string `json:"publishedDate"`
+	Title         string   `json:"title"`
+	URL           string   `json:"url"`
+	Content       string   `json:"content"`
+	Engine        string   `json:"engine"`
+	Engines       []string `json:"engines"`
+	PublishedDate string   `json:"publishedDate"`
+	// Author is a name for videos and a list of names for papers
+	Author  searXText `json:"author"`
+	Authors searXText `json:"authors"`
+	// Source is the outlet of news results, some engines leave it out
+	Source   searXText `json:"source"`
+	Category string    `json:"category"`
+}
+
+// searXText decodes a string or a list of strings, other values are ignored
+type searXText string
+
+func (t *searXText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = searXText(s)
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*t = searXText(strings.Join(list, ", "))
+	}
+	return nil
 }
 
 // SearXNGResponse represents the response structure from the SearXNG API
 type SearXNGResponse struct {
-	Results []SearXNGResult `json:"results"`
+	Results     []SearXNGResult  `json:"results"`
+	Answers     []searXAnswer    `json:"answers"`
+	Infoboxes   []SearXNGInfobox `json:"infoboxes"`
+	Suggestions []searXText      `json:"suggestions"`
+	Corrections []searXText      `json:"corrections"`
+	// UnresponsiveEngines are [engine, error] pairs
+	UnresponsiveEngines [][]searXText `json:"unresponsive_engines"`
+}
+
+// SearXNGInfobox is an infobox of the SearXNG API
+type SearXNGInfobox struct {
+	Infobox    string   `json:"infobox"`
+	ID         string   `json:"id"`
+	Content    string   `json:"content"`
+	ImgSrc     string   `json:"img_src"`
+	Engine     string   `json:"engine"`
+	Engines    []string `json:"engines"`
+	Attributes []struct {
+		Label string    `json:"label"`
+		Value searXText `json:"value"`
+	} `json:"attributes"`
+	URLs []struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"urls"`
+}
+
+// searXAnswer decodes an answer, a plain string in older SearXNG versions
+// and an object in newer ones
+type searXAnswer Answer
+
+func (a *searXAnswer) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		a.Text = text
+		return nil
+	}
+	var obj struct {
+		Answer string `json:"answer"`
+		URL    string `json:"url"`
+		Engine string `json:"engine"`
+	}
+	if err := json.Unmarshal(data, &obj); err == nil {
+		*a = searXAnswer{Text: obj.Answer, URL: obj.URL, Engine: obj.Engine}
+	}
+	return nil
 }
 
 // NewSearXNGAPISearcher creates a new instance of SearXNGAPISearcher
-// Uses the configuration from config.toml for the API endpoint
-func NewSearXNGAPISearcher(baseURL string) *SearXNGAPISearcher {
-	// Load the configuration
+// for the instance at baseURL. A nil client gets replaced with a standalone one.
+func NewSearXNGAPISearcher(baseURL string, client *http.Client) *SearXNGAPISearcher {
+	if baseURL == "" {
+		baseURL = defaultSearXURL
+	}
 	// Ensure the base URL ends with a slash
 	if !strings.HasSuffix(baseURL, "/") {
 		baseURL += "/"
 	}
+	if client == nil {
+		client = NewHTTPClient(defaultTimeout, DefaultRetryPolicy, nil)
+	}
+	name := "searxng"
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		name += " " + u.Host
+	}
 	return &SearXNGAPISearcher{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client:  client,
 		baseURL: baseURL,
+		name:    name,
+		maxBody: defaultMaxBodyBytes,
 	}
 }
 
-func (s *SearXNGAPISearcher) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
-	// Try the API endpoint first, then fall back to /search if needed
+// SetMaxBodyBytes caps the bytes read of a response, longer ones are
+// rejected as invalid
+func (s *SearXNGAPISearcher) SetMaxBodyBytes(n int64) {
+	if n > 0 {
+		s.maxBody = n
+	}
+}
+
+// SetDefaultFilters sets the categories and engines searched when the
+// options name none, empty lists leave the choice to the instance
+func (s *SearXNGAPISearcher) SetDefaultFilters(categories, engines []string) {
+	s.categories = normalizeNames(categories)
+	s.engines = normalizeNames(engines)
+}
+
+func (s *SearXNGAPISearcher) Search(ctx context.Context, query string, limit int, opts SearchOptions) (*ResultPage, error) {
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	pageno, err := cursorInt(cur, "pageno", 1)
+	if err != nil || pageno < 1 {
+		return nil, ErrInvalidCursor
+	}
+	skip, err := cursorInt(cur, cursorSkip, 0)
+	if err != nil {
+		return nil, err
+	}
+	apiResponse, err := s.fetchPage(ctx, query, opts, pageno)
+	if err != nil {
+		return nil, err
+	}
+	var extras *ResultPage
+	if pageno == 1 && skip == 0 {
+		extras = searXExtras(apiResponse)
+	}
+	if len(apiResponse.Results) == 0 {
+		// Nothing found or ran past the last page, answers may still be there
+		if extras == nil {
+			extras = &ResultPage{}
+		}
+		extras.Results = []SearchResult{}
+		return extras, nil
+	}
+
+	// Skip results with empty title or URL
+	valid := make([]SearXNGResult, 0, len(apiResponse.Results))
+	for _, result := range apiResponse.Results {
+		if result.Title == "" || result.URL == "" {
+			continue
+		}
+		valid = append(valid, result)
+	}
+
+	// Convert the API results to our SearchResult format
+	// Limit results after fetching from API
+	taken, nextSkip, more := takePage(valid, skip, limit)
+	results := make([]SearchResult, 0, len(taken))
+	for _, result := range taken {
+		engines := result.Engines
+		if len(engines) == 0 && result.Engine != "" {
+			engines = []string{result.Engine}
+		}
+		author := string(result.Author)
+		if author == "" {
+			author = string(result.Authors)
+		}
+		var source string
+		if result.Category == CategoryNews || slices.Contains(opts.Categories, CategoryNews) {
+			source = newsSource(result)
+		}
+		results = append(results, SearchResult{
+			URL:           result.URL,
+			Title:         result.Title,
+			Content:       result.Content,
+			Snippet:       result.Content,
+			Engines:       engines,
+			PublishedDate: parseDate(result.PublishedDate),
+			Author:        author,
+			Source:        source,
+		})
+	}
+	next := url.Values{}
+	if more {
+		// Continue on the same page
+		next.Set("pageno", strconv.Itoa(pageno))
+		next.Set(cursorSkip, strconv.Itoa(nextSkip))
+	} else {
+		next.Set("pageno", strconv.Itoa(pageno+1))
+	}
+	page := &ResultPage{}
+	if extras != nil {
+		page = extras
+	}
+	page.Results = results
+	page.NextCursor = encodeCursor(next)
+	return page, nil
+}
+
+// newsSource returns the outlet of a news result, the host of its url
+// when the engine doesn't name one
+func newsSource(result SearXNGResult) string {
+	if result.Source != "" {
+		return string(result.Source)
+	}
+	u, err := url.Parse(result.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// searXExtras returns a page holding the answers, infoboxes, suggestions,
+// corrections and unresponsive engines of the response
+func searXExtras(resp *SearXNGResponse) *ResultPage {
+	page := &ResultPage{}
+	for _, answer := range resp.Answers {
+		if answer.Text != "" {
+			page.Answers = append(page.Answers, Answer(answer))
+		}
+	}
+	for _, box := range resp.Infoboxes {
+		infobox := Infobox{
+			Title:    box.Infobox,
+			Content:  box.Content,
+			URL:      absoluteHTTPURL(box.ID),
+			ImageURL: box.ImgSrc,
+			Engines:  box.Engines,
+		}
+		if len(infobox.Engines) == 0 && box.Engine != "" {
+			infobox.Engines = []string{box.Engine}
+		}
+		for _, attr := range box.Attributes {
+			if attr.Label != "" && attr.Value != "" {
+				infobox.Attributes = append(infobox.Attributes, InfoboxAttribute{Label: attr.Label, Value: string(attr.Value)})
+			}
+		}
+		for _, link := range box.URLs {
+			if link.URL != "" {
+				infobox.Links = append(infobox.Links, InfoboxLink{Title: link.Title, URL: link.URL})
+			}
+		}
+		page.Infoboxes = append(page.Infoboxes, infobox)
+	}
+	for _, suggestion := range resp.Suggestions {
+		if suggestion != "" {
+			page.Suggestions = append(page.Suggestions, string(suggestion))
+		}
+	}
+	for _, correction := range resp.Corrections {
+		if correction != "" {
+			page.Corrections = append(page.Corrections, string(correction))
+		}
+	}
+	for _, pair := range resp.UnresponsiveEngines {
+		if len(pair) == 0 || pair[0] == "" {
+			continue
+		}
+		engine := UnresponsiveEngine{Engine: string(pair[0])}
+		if len(pair) > 1 {
+			engine.Error = string(pair[1])
+		}
+		page.UnresponsiveEngines = append(page.UnresponsiveEngines, engine)
+	}
+	return page
+}
+
+// fetchPage requests one page of results, trying the API endpoint first
+// and falling back to /search if needed
+func (s *SearXNGAPISearcher) fetchPage(ctx context.Context, query string, opts SearchOptions, pageno int) (*SearXNGResponse, error) {
 	endpoints := []string{"/api/v1/search", "/search"}
 	var apiResponse SearXNGResponse
 
+	parsed := false
+	var lastErr error
 	for _, endpoint := range endpoints {
 		// Build the API URL
 		apiURL := fmt.Sprintf("%s%s", s.baseURL, strings.TrimPrefix(endpoint, "/"))
@@ -62,6 +316,26 @@ func (s *SearXNGAPISearcher) Search(ctx context.Context, query string, limit int
 		params := url.Values{}
 		params.Set("q", query)
 		params.Set("format", "json")
+		params.Set("pageno", strconv.Itoa(pageno))
+		if lang := opts.searXLanguage(); lang != "" {
+			params.Set("language", lang)
+		}
+		if opts.TimeRange != TimeRangeAny {
+			params.Set("time_range", string(opts.TimeRange))
+		}
+		if safe := opts.searXSafeSearch(); safe != "" {
+			params.Set("safesearch", safe)
+		}
+		categories, engines := opts.Categories, opts.Engines
+		if len(categories) == 0 && len(engines) == 0 {
+			categories, engines = s.categories, s.engines
+		}
+		if len(categories) > 0 {
+			params.Set("categories", strings.Join(categories, ","))
+		}
+		if len(engines) > 0 {
+			params.Set("engines", strings.Join(engines, ","))
+		}
 
 		// Note: SearXNG API doesn't have a direct limit parameter in URL by default,
 		// so we'll fetch results and limit them after parsing
@@ -86,11 +360,17 @@ func (s *SearXNGAPISearcher) Search(ctx context.Context, query string, limit int
 		// Execute the request
 		resp, err := s.client.Do(req)
 		if err != nil {
+			lastErr = transportError(s.name, err)
+			continue // Try next endpoint
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = statusError(s.name, resp)
+			resp.Body.Close()
 			continue // Try next endpoint
 		}
 
 		// Read the response body
-		var body []byte
+		var reader io.ReadCloser = resp.Body
 		if resp.Header.Get("Content-Encoding") == "gzip" {
 			// Handle gzipped response
 			gzipReader, err := gzip.NewReader(resp.Body)
@@ -98,52 +378,30 @@ func (s *SearXNGAPISearcher) Search(ctx context.Context, query string, limit int
 				resp.Body.Close()
 				continue // Try next endpoint
 			}
-			body, err = io.ReadAll(gzipReader)
-			gzipReader.Close()
-			if err != nil {
-				resp.Body.Close()
-				continue // Try next endpoint
-			}
-		} else {
-			body, err = io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				continue // Try next endpoint
-			}
+			reader = gzipReader
 		}
-
-		// Try to parse the JSON response
-		if err := json.Unmarshal(body, &apiResponse); err != nil {
+		// The limit applies after decompression, so small bombs can't blow up
+		body := newCappedReader(reader, s.maxBody)
+		err = json.NewDecoder(body).Decode(&apiResponse)
+		reader.Close()
+		resp.Body.Close()
+		if body.exceeded {
+			err = fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, s.maxBody)
+		}
+		if err != nil {
+			lastErr = &BackendError{Backend: s.name, Err: fmt.Errorf("%w: %w", ErrInvalidResponse, err)}
 			continue // Try next endpoint
-		} else {
-			// Successfully parsed JSON, break the loop
-			break
 		}
+		// Successfully parsed JSON, break the loop
+		parsed = true
+		break
 	}
 
-	if len(apiResponse.Results) == 0 {
-		return nil, errors.New("no valid JSON response from any endpoint")
-	}
-
-	// Convert the API results to our SearchResult format
-	// Limit results after fetching from API
-	results := make([]SearchResult, 0, limit)
-	for _, result := range apiResponse.Results {
-		if len(results) >= limit {
-			break
+	if !parsed {
+		if lastErr == nil {
+			lastErr = &BackendError{Backend: s.name, Err: ErrInvalidResponse}
 		}
-
-		// Skip results with empty title or URL
-		if result.Title == "" || result.URL == "" {
-			continue
-		}
-
-		results = append(results, SearchResult{
-			URL:     result.URL,
-			Title:   result.Title,
-			Content: result.Content,
-		})
+		return nil, lastErr
 	}
-
-	return results, nil
+	return &apiResponse, nil
 }
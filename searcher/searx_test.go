@@ -0,0 +1,35 @@
+package searcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearXNGEmptyPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantAnswers int
+	}{
+		{"nothing found", `{"results": []}`, 0},
+		{"answer only", `{"results": [], "answers": ["42"]}`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			page, err := NewSearXNGAPISearcher(srv.URL, srv.Client()).Search(context.Background(), "q", 5, SearchOptions{})
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if page.Results == nil || len(page.Results) != 0 || len(page.Answers) != tt.wantAnswers {
+				t.Errorf("page = %+v, want no results and %d answers", page, tt.wantAnswers)
+			}
+		})
+	}
+}
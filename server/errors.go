@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/GrailFinder/searchagent/searcher"
+)
+
+// Machine-readable error codes of the json error body
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeRateLimited        = "rate_limited"
+	CodeBlocked            = "blocked"
+	CodeTimeout            = "timeout"
+	CodeBackendUnavailable = "backend_unavailable"
+	CodeInvalidResponse    = "invalid_response"
+	CodeNoResults          = "no_results"
+	CodeInternal           = "internal_error"
+)
+
+// codeMessages are the messages sent for search errors of each code
+var codeMessages = map[string]string{
+	CodeRateLimited:        "Search backends are rate limiting us, try again later",
+	CodeBlocked:            "Search backends are blocking us, try again later",
+	CodeTimeout:            "Search backends took too long to answer",
+	CodeBackendUnavailable: "No search backend is available",
+	CodeInvalidResponse:    "Search backends returned an invalid response",
+	CodeNoResults:          "No results found",
+	CodeInternal:           "Search failed",
+}
+
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// RetryAfter is the number of seconds to wait before retrying, if known
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// searchErrorStatus maps a search error to an http status and error code.
+// Errors joined from several backends map to the first matching kind.
+func searchErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, searcher.ErrUnknownSearchType), errors.Is(err, searcher.ErrInvalidCursor),
+		errors.Is(err, searcher.ErrInvalidLimit):
+		return http.StatusBadRequest, CodeInvalidRequest
+	case errors.Is(err, searcher.ErrRateLimited):
+		return http.StatusTooManyRequests, CodeRateLimited
+	case errors.Is(err, searcher.ErrBlocked):
+		return http.StatusServiceUnavailable, CodeBlocked
+	case errors.Is(err, searcher.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout
+	case errors.Is(err, searcher.ErrBackendUnavailable), errors.Is(err, searcher.ErrNoBackend):
+		return http.StatusBadGateway, CodeBackendUnavailable
+	case errors.Is(err, searcher.ErrInvalidResponse):
+		return http.StatusBadGateway, CodeInvalidResponse
+	case errors.Is(err, searcher.ErrNoResults):
+		return http.StatusNotFound, CodeNoResults
+	case errors.Is(err, searcher.ErrUnsupportedFilter):
+		// Only when no backend of the search knows the filter
+		return http.StatusBadRequest, CodeInvalidRequest
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
+
+// writeSearchError answers with the status and code matching a search error
+func writeSearchError(w http.ResponseWriter, err error) {
+	status, code := searchErrorStatus(err)
+	// Backend errors name the hosts of private instances, their details
+	// stay in the log. Invalid requests only echo what the client sent.
+	message := codeMessages[code]
+	if code == CodeInvalidRequest {
+		message = err.Error()
+	}
+	retryAfter := int(math.Ceil(searcher.RetryAfter(err).Seconds()))
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	writeError(w, status, ErrorDetail{Code: code, Message: message, RetryAfter: retryAfter})
+}
+
+// writeError encodes the error detail as the json body
+func writeError(w http.ResponseWriter, status int, detail ErrorDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: detail}); err != nil {
+		slog.Error("Failed to encode error response", "error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GrailFinder/searchagent/searcher"
+)
+
+func TestSearchErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"unknown type", fmt.Errorf("%w: foo", searcher.ErrUnknownSearchType), http.StatusBadRequest, CodeInvalidRequest},
+		{"invalid cursor", searcher.ErrInvalidCursor, http.StatusBadRequest, CodeInvalidRequest},
+		{"invalid limit", searcher.ErrInvalidLimit, http.StatusBadRequest, CodeInvalidRequest},
+		{"rate limited", &searcher.BackendError{Backend: "api", StatusCode: 429, Err: searcher.ErrRateLimited}, http.StatusTooManyRequests, CodeRateLimited},
+		{"blocked", &searcher.BackendError{Backend: "duckduckgo", Err: searcher.ErrBlocked}, http.StatusServiceUnavailable, CodeBlocked},
+		{"timeout", &searcher.BackendError{Backend: "api", Err: searcher.ErrTimeout}, http.StatusGatewayTimeout, CodeTimeout},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+		{"unavailable", &searcher.BackendError{Backend: "api", StatusCode: 502, Err: searcher.ErrBackendUnavailable}, http.StatusBadGateway, CodeBackendUnavailable},
+		{"no backend", searcher.ErrNoBackend, http.StatusBadGateway, CodeBackendUnavailable},
+		{"invalid response", &searcher.BackendError{Backend: "api", Err: searcher.ErrInvalidResponse}, http.StatusBadGateway, CodeInvalidResponse},
+		{"no results", searcher.ErrNoResults, http.StatusNotFound, CodeNoResults},
+		{"unsupported filter", fmt.Errorf("%w: engines github", searcher.ErrUnsupportedFilter), http.StatusBadRequest, CodeInvalidRequest},
+		{"joined takes the first kind", errors.Join(
+			fmt.Errorf("scraper: %w", searcher.ErrUnsupportedFilter),
+			&searcher.BackendError{Backend: "api", Err: searcher.ErrRateLimited},
+		), http.StatusTooManyRequests, CodeRateLimited},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := searchErrorStatus(tt.err)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("searchErrorStatus = %d %s, want %d %s", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestWriteSearchError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantMessage    string
+		wantRetryAfter int
+	}{
+		{
+			"backend details stay private",
+			&searcher.BackendError{Backend: "searxng internal.example", StatusCode: 502, Err: searcher.ErrBackendUnavailable},
+			"No search backend is available", 0,
+		},
+		{
+			"retry after is rounded up",
+			&searcher.BackendError{Backend: "api", RetryAfter: 1500 * time.Millisecond, Err: searcher.ErrRateLimited},
+			"Search backends are rate limiting us, try again later", 2,
+		},
+		{
+			"invalid requests echo the error",
+			searcher.ErrInvalidCursor,
+			"invalid cursor", 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeSearchError(rec, tt.err)
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding the error body: %v", err)
+			}
+			if body.Error.Message != tt.wantMessage || body.Error.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("error = %+v, want message %q and retry after %d", body.Error, tt.wantMessage, tt.wantRetryAfter)
+			}
+			if tt.wantRetryAfter > 0 && rec.Header().Get("Retry-After") != fmt.Sprint(tt.wantRetryAfter) {
+				t.Errorf("Retry-After header = %q, want %d", rec.Header().Get("Retry-After"), tt.wantRetryAfter)
+			}
+		})
+	}
+}
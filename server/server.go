@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GrailFinder/searchagent/config"
@@ -18,12 +19,53 @@ type SearchRequest struct {
 	Query      string `json:"query"`
 	SearchType string `json:"search_type"`
 	NumResults int    `json:"num_results"`
+	Language   string `json:"language"`
+	Region     string `json:"region"`
+	SafeSearch string `json:"safe_search"`
+	TimeRange  string `json:"time_range"`
+	// ContentFormat is text, markdown or html-clean
+	ContentFormat string `json:"content_format"`
+	// Categories and Engines focus SearXNG searches
+	Categories []string `json:"categories"`
+	Engines    []string `json:"engines"`
+	// Sort is relevance or date
+	Sort   string `json:"sort"`
+	Cursor string `json:"cursor"`
+}
+
+// Options returns the search options of the request
+func (req SearchRequest) Options() searcher.SearchOptions {
+	return searcher.SearchOptions{
+		Language:   req.Language,
+		Region:     req.Region,
+		SafeSearch: searcher.SafeSearch(req.SafeSearch),
+		TimeRange:  searcher.TimeRange(req.TimeRange),
+		Format:     searcher.ContentFormat(req.ContentFormat),
+		Categories: req.Categories,
+		Engines:    req.Engines,
+		Sort:       searcher.SortOrder(req.Sort),
+		Cursor:     req.Cursor,
+	}
 }
 
 type ServerSearchResult struct {
-	Title   string `json:"title"`
-	URL     string `json:"url"`
-	Content string `json:"content"`
+	Title         string     `json:"title"`
+	URL           string     `json:"url"`
+	Content       string     `json:"content"`
+	Snippet       string     `json:"snippet,omitempty"`
+	Engines       []string   `json:"engines,omitempty"`
+	PublishedDate *time.Time `json:"published_date,omitempty"`
+	Author        string     `json:"author,omitempty"`
+	SiteName      string     `json:"site_name,omitempty"`
+	Source        string     `json:"source,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	CanonicalURL  string     `json:"canonical_url,omitempty"`
+	FinalURL      string     `json:"final_url,omitempty"`
+	ContentLength int64      `json:"content_length,omitempty"`
+	FetchStatus   int        `json:"fetch_status,omitempty"`
+	// Metadata is the OpenGraph, meta tag and JSON-LD data of the page
+	Metadata *searcher.PageMetadata `json:"metadata,omitempty"`
+	Flags    []string               `json:"flags,omitempty"`
 }
 
 type SearchResponse struct {
@@ -31,12 +73,22 @@ type SearchResponse struct {
 	Results    []ServerSearchResult `json:"results"`
 	Timestamp  time.Time            `json:"timestamp"`
 	TotalCount int                  `json:"total_count"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	Backend    string               `json:"backend"`
+	Cached     bool                 `json:"cached"`
+	// Answers and infoboxes answer the query without fetching any page
+	Answers   []searcher.Answer  `json:"answers,omitempty"`
+	Infoboxes []searcher.Infobox `json:"infoboxes,omitempty"`
+	// Suggestions are related queries, corrections "did you mean" hints
+	Suggestions         []string                      `json:"suggestions,omitempty"`
+	Corrections         []string                      `json:"corrections,omitempty"`
+	UnresponsiveEngines []searcher.UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
 }
 
 // searchHandler handles incoming search requests
 func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost && r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrorDetail{Code: CodeMethodNotAllowed, Message: "Method not allowed"})
 		return
 	}
 	var req SearchRequest
@@ -45,18 +97,28 @@ func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
 		// Parse JSON request body
 		decoder := json.NewDecoder(r.Body)
 		if err := decoder.Decode(&req); err != nil {
-			http.Error(w, "Invalid JSON in request body", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, ErrorDetail{Code: CodeInvalidRequest, Message: "Invalid JSON in request body"})
 			return
 		}
 	case http.MethodGet:
 		// Parse query parameters from URL
 		req.Query = r.URL.Query().Get("q")
 		req.SearchType = r.URL.Query().Get("type")
+		req.Language = r.URL.Query().Get("lang")
+		req.Region = r.URL.Query().Get("region")
+		req.SafeSearch = r.URL.Query().Get("safe")
+		req.TimeRange = r.URL.Query().Get("time")
+		req.ContentFormat = r.URL.Query().Get("format")
+		// Comma separated lists, split by Validate
+		req.Categories = r.URL.Query()["categories"]
+		req.Engines = r.URL.Query()["engines"]
+		req.Sort = r.URL.Query().Get("sort")
+		req.Cursor = r.URL.Query().Get("cursor")
 		numResultsStr := r.URL.Query().Get("num")
 		if numResultsStr != "" {
 			numResults, err := strconv.Atoi(numResultsStr)
 			if err != nil {
-				http.Error(w, "Invalid num_results parameter", http.StatusBadRequest)
+				writeError(w, http.StatusBadRequest, ErrorDetail{Code: CodeInvalidRequest, Message: "Invalid num_results parameter"})
 				return
 			}
 			req.NumResults = numResults
@@ -64,34 +126,61 @@ func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	// Set defaults if not provided
 	if req.SearchType == "" {
-		req.SearchType = "general" // Default to general search
+		req.SearchType = searcher.TypeGeneral // Default to general search
 	}
 	if req.NumResults <= 0 {
 		req.NumResults = 10 // Default number of results
 	}
 	if req.Query == "" {
-		http.Error(w, "Query parameter is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrorDetail{Code: CodeInvalidRequest, Message: "Query parameter is required"})
+		return
+	}
+	opts := req.Options()
+	if err := opts.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorDetail{Code: CodeInvalidRequest, Message: err.Error()})
 		return
 	}
 	// Perform the search using the existing functionality
-	results, err := s.Search(req.Query, req.SearchType, req.NumResults)
+	page, err := s.Search(r.Context(), req.Query, req.SearchType, req.NumResults, opts)
 	if err != nil {
 		slog.Error("Search failed", "error", err)
-		http.Error(w, "Search failed", http.StatusInternalServerError)
+		writeSearchError(w, err)
 		return
 	}
 	// Prepare response
 	response := SearchResponse{
 		Query:      req.Query,
-		Results:    make([]ServerSearchResult, len(results)),
+		Results:    make([]ServerSearchResult, len(page.Results)),
 		Timestamp:  time.Now(),
-		TotalCount: len(results),
+		TotalCount: len(page.Results),
+		NextCursor: page.NextCursor,
+		Backend:    page.Backend,
+		Cached:     page.Cached,
+
+		Answers:             page.Answers,
+		Infoboxes:           page.Infoboxes,
+		Suggestions:         page.Suggestions,
+		Corrections:         page.Corrections,
+		UnresponsiveEngines: page.UnresponsiveEngines,
 	}
-	for i, result := range results {
+	for i, result := range page.Results {
 		response.Results[i] = ServerSearchResult{
-			Title:   result.Title,
-			URL:     result.URL,
-			Content: result.Content,
+			Title:         result.Title,
+			URL:           result.URL,
+			Content:       result.Content,
+			Snippet:       result.Snippet,
+			Engines:       result.Engines,
+			PublishedDate: result.PublishedDate,
+			Author:        result.Author,
+			SiteName:      result.SiteName,
+			Source:        result.Source,
+			Language:      result.Language,
+			CanonicalURL:  result.CanonicalURL,
+			FinalURL:      result.FinalURL,
+			ContentLength: result.ContentLength,
+			FetchStatus:   result.FetchStatus,
+			Metadata:      result.Metadata,
+			Flags:         result.Flags,
 		}
 	}
 	// Set content type and encode response as JSON
@@ -104,7 +193,7 @@ func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
 // describeHandler returns the tool schema for LLM consumption
 func (s *Server) describeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrorDetail{Code: CodeMethodNotAllowed, Message: "Method not allowed"})
 		return
 	}
 	// Define the search tool schema
@@ -122,12 +211,50 @@ func (s *Server) describeHandler(w http.ResponseWriter, r *http.Request) {
 					},
 					"search_type": {
 						Type:        "string",
-						Description: "Type of search to perform: 'api' for SearXNG API search or 'scraper' for web scraping (default: 'scraper')",
+						Description: "Type of search to perform: 'api' for SearXNG API search, 'scraper' for web scraping, 'all' to merge results of every backend, 'news' for recent news articles with their source and publish time, or 'fallback' to try backends in order until one has results (default: 'fallback'). Known types: " + strings.Join(s.registry.Names(), ", "),
 					},
 					"num_results": {
 						Type:        "integer",
 						Description: "Maximum number of results to return (default: 10)",
 					},
+					"language": {
+						Type:        "string",
+						Description: "Two letter language code of the results, e.g. 'en' (default: any)",
+					},
+					"region": {
+						Type:        "string",
+						Description: "Two letter country code to localize the results, e.g. 'us' (default: worldwide)",
+					},
+					"safe_search": {
+						Type:        "string",
+						Description: "Adult content filtering: 'off', 'moderate' or 'strict' (default: backend setting)",
+					},
+					"time_range": {
+						Type:        "string",
+						Description: "Only return results from the last 'day', 'week', 'month' or 'year' (default: any time)",
+					},
+					"content_format": {
+						Type:        "string",
+						Description: "Format of fetched page content: 'text', 'markdown' keeping headings, lists, links and code, or 'html-clean' (default: text)",
+					},
+					"categories": {
+						Type:        "array",
+						Description: "SearXNG result categories to search, e.g. 'general', 'news', 'science', 'it', 'q&a' or 'repos' (default: instance setting). The scraper only knows 'general' and 'news', the default type sends other categories to SearXNG",
+						Items:       &models.ToolArgProps{Type: "string"},
+					},
+					"engines": {
+						Type:        "array",
+						Description: "SearXNG engines to search, e.g. ['github', 'stackoverflow'] for a focused search (default: instance setting). Only SearXNG search types use it, the default type sends such searches to SearXNG",
+						Items:       &models.ToolArgProps{Type: "string"},
+					},
+					"sort": {
+						Type:        "string",
+						Description: "Order of the results: 'relevance' or 'date' for the latest first (default: relevance, date for news)",
+					},
+					"cursor": {
+						Type:        "string",
+						Description: "The next_cursor of a previous response to get more results of the same search",
+					},
 				},
 				Required: []string{"query"},
 			},
@@ -143,40 +270,25 @@ func (s *Server) describeHandler(w http.ResponseWriter, r *http.Request) {
 
 // Server represents the HTTP server
 type Server struct {
-	config *config.Config
+	config   *config.Config
+	registry *searcher.Registry
 }
 
-// NewServer creates a new server instance
-func NewServer(cfg *config.Config) *Server {
-	return &Server{
-		config: cfg,
-	}
-}
-
-// Search performs a search with the given parameters
-func (s *Server) Search(query string, searchType string, numResults int) ([]searcher.SearchResult, error) {
-	var sr searcher.Searcher
-	var err error
-	switch searchType {
-	case "api":
-		sr, err = searcher.NewSearchService(searcher.SearcherTypeAPI, "")
-	case "scraper":
-		fallthrough
-	default:
-		sr, err = searcher.NewSearchService(searcher.SearcherTypeScraper, "")
-	}
-
+// NewServer creates a new server instance with searchers built from the config
+func NewServer(cfg *config.Config) (*Server, error) {
+	registry, err := searcher.NewRegistry(cfg)
 	if err != nil {
-		slog.Error("Failed to create searcher", "error", err)
 		return nil, err
 	}
+	return &Server{
+		config:   cfg,
+		registry: registry,
+	}, nil
+}
 
-	ctx := context.Background()
-	results, err := sr.Search(ctx, query, numResults)
-	if err != nil {
-		return nil, err
-	}
-	return results, nil
+// Search performs a search with the given parameters
+func (s *Server) Search(ctx context.Context, query string, searchType string, numResults int, opts searcher.SearchOptions) (*searcher.ResultPage, error) {
+	return s.registry.Search(ctx, searchType, query, numResults, opts)
 }
 
 // Start starts the HTTP server